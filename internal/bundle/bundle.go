@@ -0,0 +1,161 @@
+// Package bundle seals a resolved environment set into a single encrypted
+// file that can be shared and later decrypted with a passphrase.
+//
+// The passphrase is stretched with PBKDF2-HMAC-SHA256 and the payload is
+// encrypted with AES-256-GCM; the envelope header is authenticated as
+// additional data so it cannot be altered without detection.
+package bundle
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	formatVersion = 1
+	kdfName       = "pbkdf2-sha256"
+	// DefaultIterations follows current OWASP guidance for PBKDF2-HMAC-SHA256
+	DefaultIterations = 600000
+	// maxIterations bounds the work a bundle can demand before it is decrypted
+	maxIterations = 10 * DefaultIterations
+	saltSize      = 16
+	keySize       = 32
+)
+
+// ErrDecrypt is returned when the passphrase is wrong or the bundle was modified
+var ErrDecrypt = errors.New("failed to decrypt bundle: wrong passphrase or corrupted file")
+
+// Bundle is the plaintext content: resolved values per environment
+type Bundle struct {
+	Vault        string                       `json:"vault"`
+	Environments map[string]map[string]string `json:"environments"`
+}
+
+// envelope is the on-disk format
+type envelope struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// header returns the authenticated additional data for an envelope
+func (e *envelope) header() []byte {
+	return []byte(fmt.Sprintf("yeet-bundle:v%d:%s:%d:%x", e.Version, e.KDF, e.Iterations, e.Salt))
+}
+
+// Seal encrypts b with a key derived from passphrase
+func Seal(b *Bundle, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("bundle passphrase cannot be empty")
+	}
+
+	plaintext, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+
+	env := &envelope{
+		Version:    formatVersion,
+		KDF:        kdfName,
+		Iterations: DefaultIterations,
+		Salt:       make([]byte, saltSize),
+	}
+	if _, err := rand.Read(env.Salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(passphrase, env)
+	if err != nil {
+		return nil, err
+	}
+	env.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(env.Nonce); err != nil {
+		return nil, err
+	}
+	env.Ciphertext = aead.Seal(nil, env.Nonce, plaintext, env.header())
+
+	return json.MarshalIndent(env, "", "  ")
+}
+
+// Open decrypts data produced by Seal
+func Open(data []byte, passphrase string) (*Bundle, error) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	if env.Version != formatVersion || env.KDF != kdfName {
+		return nil, fmt.Errorf("unsupported bundle format v%d (%s)", env.Version, env.KDF)
+	}
+	if env.Iterations < 1 || len(env.Salt) == 0 {
+		return nil, fmt.Errorf("invalid bundle: missing key derivation parameters")
+	}
+	if env.Iterations > maxIterations {
+		return nil, fmt.Errorf("invalid bundle: %d key derivation iterations exceeds the limit of %d", env.Iterations, maxIterations)
+	}
+
+	aead, err := newAEAD(passphrase, &env)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid bundle: bad nonce")
+	}
+
+	plaintext, err := aead.Open(nil, env.Nonce, env.Ciphertext, env.header())
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	var b Bundle
+	if err := json.Unmarshal(plaintext, &b); err != nil {
+		return nil, fmt.Errorf("invalid bundle payload: %w", err)
+	}
+	return &b, nil
+}
+
+// WriteFile seals b and writes it to path with owner-only permissions
+func WriteFile(path string, b *Bundle, passphrase string) error {
+	data, err := Seal(b, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write bundle %s: %w", path, err)
+	}
+	// WriteFile keeps the mode of a file it overwrites
+	if err := os.Chmod(path, 0600); err != nil {
+		return fmt.Errorf("failed to set permissions on bundle %s: %w", path, err)
+	}
+	return nil
+}
+
+// ReadFile reads and decrypts the bundle at path
+func ReadFile(path, passphrase string) (*Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle %s: %w", path, err)
+	}
+	return Open(data, passphrase)
+}
+
+func newAEAD(passphrase string, env *envelope) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, env.Salt, env.Iterations, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+// materializeBinarySecrets base64-decodes values of mappings marked binary,
+// writes them to <filesDir>/<environment>/<KEY> and replaces the result value
+// with that path, so binary content never ends up inside a dotenv file
+func materializeBinarySecrets(results []secretResult, cfg *config.Config, filesDir string) error {
+	for i := range results {
+		r := &results[i]
+		mapping := cfg.Mappings[r.key]
+		spec := cfg.ValueSpec(mapping, r.environment)
+		if spec == nil || !spec.Binary {
+			continue
+		}
+
+		data, err := base64.StdEncoding.DecodeString(r.value)
+		if err != nil {
+			return fmt.Errorf("binary value for %s (%s) is not valid base64: %w", r.key, r.environment, err)
+		}
+
+		dir := filepath.Join(filesDir, string(r.environment))
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		path := filepath.Join(dir, r.key)
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		// WriteFile keeps the mode of an existing file, so enforce it
+		if err := os.Chmod(path, 0600); err != nil {
+			return err
+		}
+
+		ui.Info("wrote binary secret %s (%s) to %s", r.key, r.environment, path)
+		r.value = path
+	}
+	return nil
+}
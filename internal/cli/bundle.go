@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/JayDubyaEey/yeet/internal/bundle"
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+const defaultPassphraseEnv = "YEET_BUNDLE_PASSPHRASE"
+
+// bundlePassphraseEnv names the env var holding the bundle passphrase
+var bundlePassphraseEnv = defaultPassphraseEnv
+
+func bundlePassphrase() (string, error) {
+	passphrase := os.Getenv(bundlePassphraseEnv)
+	if passphrase == "" {
+		return "", fmt.Errorf("bundle passphrase not set: export %s", bundlePassphraseEnv)
+	}
+	return passphrase, nil
+}
+
+// writeBundle seals the resolved values for both environments into path
+func writeBundle(path, vault string, envMap, dockerMap map[string]string) error {
+	passphrase, err := bundlePassphrase()
+	if err != nil {
+		return err
+	}
+
+	b := &bundle.Bundle{
+		Vault: vault,
+		Environments: map[string]map[string]string{
+			"local":  envMap,
+			"docker": dockerMap,
+		},
+	}
+	if err := bundle.WriteFile(path, b, passphrase); err != nil {
+		return err
+	}
+	ui.Success("wrote encrypted bundle %s", path)
+	return nil
+}
+
+// loadBundleEnv decrypts path and returns the values for environment env
+func loadBundleEnv(path, env string) (map[string]string, error) {
+	environment, err := parseEnvironment(env)
+	if err != nil {
+		return nil, err
+	}
+
+	passphrase, err := bundlePassphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := bundle.ReadFile(path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := b.Environments[string(environment)]
+	if !ok {
+		return nil, fmt.Errorf("bundle %s has no values for environment %s", path, environment)
+	}
+
+	// Copy so overrides never modify the decoded bundle
+	envVars := make(map[string]string, len(values))
+	for k, v := range values {
+		envVars[k] = v
+	}
+	ui.Success("loaded %d environment variables from bundle %s (vault %s)", len(envVars), path, b.Vault)
+	return envVars, nil
+}
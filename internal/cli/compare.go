@@ -1,8 +1,12 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 
@@ -15,6 +19,9 @@ import (
 
 var (
 	deploymentPath string
+	deploymentDir  string
+	stripPrefixes  []string
+	checkRefs      bool
 )
 
 func newCompareCmd() *cobra.Command {
@@ -30,7 +37,10 @@ This helps identify:
 - Potential mismatches or unused configurations`,
 		Example: `  yeet compare
   yeet compare --deployment deploy/prod/deployment.yml
-  yeet compare -d k8s/deployment.yaml`,
+  yeet compare -d k8s/deployment.yaml
+  yeet compare --deployment-dir deploy/
+  yeet compare --strip-prefix SIDECAR_
+  yeet compare --check-refs`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runCompare()
 		},
@@ -38,6 +48,12 @@ This helps identify:
 
 	cmd.Flags().StringVarP(&deploymentPath, "deployment", "d", "deploy/manifests/base/deployment.yaml",
 		"Path to Kubernetes deployment YAML file")
+	cmd.Flags().StringVar(&deploymentDir, "deployment-dir", "",
+		"Scan every YAML file under this directory instead of a single deployment file")
+	cmd.Flags().StringSliceVar(&stripPrefixes, "strip-prefix", nil,
+		"Remove this prefix from deployment variable names before comparing (repeatable)")
+	cmd.Flags().BoolVar(&checkRefs, "check-refs", false,
+		"Also check that valueFrom.secretKeyRef keys are variables or Key Vault secrets in the config")
 
 	return cmd
 }
@@ -88,96 +104,251 @@ type ConfigMapKeyRef struct {
 
 // ComparisonResult holds the result of comparing config vs deployment
 type ComparisonResult struct {
-	ConfigVars       []string // Variables defined in config
-	DeploymentVars   []string // Variables defined in deployment
-	InConfigOnly     []string // Variables in config but not in deployment
-	InDeploymentOnly []string // Variables in deployment but not in config
-	Matching         []string // Variables in both config and deployment
+	ConfigVars       []string            // Variables defined in config
+	DeploymentVars   []string            // Variables defined in deployment
+	InConfigOnly     []string            // Variables in config but not in deployment
+	InDeploymentOnly []string            // Variables in deployment but not in config
+	Matching         []string            // Variables in both config and deployment
+	Sources          map[string][]string // Deployment variable -> "file (container)" locations
+}
+
+// secretRef is a valueFrom.secretKeyRef found in a deployment
+type secretRef struct {
+	envName  string
+	secret   string
+	key      string
+	location string
 }
 
 func runCompare() error {
 	// Load configuration
-	cfg, err := config.Load(configPath)
+	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
-	// Check if deployment file exists
-	if _, err := os.Stat(deploymentPath); os.IsNotExist(err) {
-		return fmt.Errorf("deployment file not found: %s", deploymentPath)
-	}
+	// Parse deployment file(s)
+	source := deploymentPath
+	var sources map[string][]string
+	var refs []secretRef
+	if deploymentDir != "" {
+		source = deploymentDir
+		sources, err = extractEnvVarsFromDir(deploymentDir, &refs)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Check if deployment file exists
+		if _, err := os.Stat(deploymentPath); os.IsNotExist(err) {
+			return fmt.Errorf("deployment file not found: %s", deploymentPath)
+		}
 
-	// Parse deployment file
-	deploymentVars, err := extractEnvVarsFromDeployment(deploymentPath)
-	if err != nil {
-		return fmt.Errorf("failed to parse deployment file: %w", err)
+		sources = make(map[string][]string)
+		if err := extractEnvVarsFromDeployment(deploymentPath, sources, &refs); err != nil {
+			return fmt.Errorf("failed to parse deployment file: %w", err)
+		}
 	}
+	if len(stripPrefixes) > 0 {
+		sources = stripDeploymentPrefixes(sources, stripPrefixes)
+	}
+	deploymentVars := sortedKeys(sources)
 
 	// Extract config variables
 	configVars := extractConfigVars(cfg)
 
 	// Compare and generate result
 	result := compareVars(configVars, deploymentVars)
+	result.Sources = sources
 
 	// Display results
-	displayComparisonResult(result, deploymentPath)
+	displayComparisonResult(result, source)
+	if checkRefs {
+		displaySecretRefs(unmanagedSecretRefs(cfg, refs), len(refs))
+	}
 
 	return nil
 }
 
-func extractEnvVarsFromDeployment(filePath string) ([]string, error) {
-	data, err := os.ReadFile(filePath)
+// workloadKinds are the resources whose pod template lives at spec.template.spec
+var workloadKinds = map[string]bool{
+	"Deployment":  true,
+	"StatefulSet": true,
+	"DaemonSet":   true,
+	"ReplicaSet":  true,
+	"Job":         true,
+}
+
+// extractEnvVarsFromDir walks dir and collects env vars from every YAML file in it
+func extractEnvVarsFromDir(dir string, refs *[]secretRef) (map[string][]string, error) {
+	info, err := os.Stat(dir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read deployment file: %w", err)
+		return nil, fmt.Errorf("deployment directory not found: %s", dir)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", dir)
 	}
 
-	var envVars []string
-	envVarSet := make(map[string]bool) // Use set to avoid duplicates
+	sources := make(map[string][]string)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+		if err := extractEnvVarsFromDeployment(path, sources, refs); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
 
-	// Split YAML documents (in case there are multiple documents in one file)
-	documents := strings.Split(string(data), "---")
+// extractEnvVarsFromDeployment adds every container env var in filePath to
+// sources, keyed by name, recording the file and container it came from, and
+// appends any secretKeyRef it finds to refs
+func extractEnvVarsFromDeployment(filePath string, sources map[string][]string, refs *[]secretRef) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read deployment file: %w", err)
+	}
+	defer file.Close()
+
+	// Decode each YAML document in turn (a file may contain several)
+	decoder := yaml.NewDecoder(file)
+	for docIndex := 1; ; docIndex++ {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("malformed YAML in document %d: %w", docIndex, err)
+		}
 
-	for _, doc := range documents {
-		doc = strings.TrimSpace(doc)
-		if doc == "" {
+		// Empty documents (e.g. a leading or trailing ---) carry no content
+		if len(node.Content) == 0 {
 			continue
 		}
 
-		var deployment KubernetesDeployment
-		if err := yaml.Unmarshal([]byte(doc), &deployment); err != nil {
-			// Skip non-deployment documents or malformed YAML
+		var meta struct {
+			Kind string `yaml:"kind"`
+		}
+		if err := node.Decode(&meta); err != nil {
+			// Not a mapping, so not a Kubernetes resource we care about
+			ui.Warn("skipping document %d: not a Kubernetes resource", docIndex)
 			continue
 		}
 
-		// Only process Deployment resources
-		if deployment.Kind != "Deployment" {
+		// Only process workload resources
+		if !workloadKinds[meta.Kind] {
 			continue
 		}
 
+		var deployment KubernetesDeployment
+		if err := node.Decode(&deployment); err != nil {
+			return fmt.Errorf("invalid %s in document %d: %w", meta.Kind, docIndex, err)
+		}
+
 		// Extract environment variables from all containers
 		for _, container := range deployment.Spec.Template.Spec.Containers {
+			location := fmt.Sprintf("%s (%s)", filePath, container.Name)
 			for _, env := range container.Env {
-				if !envVarSet[env.Name] {
-					envVars = append(envVars, env.Name)
-					envVarSet[env.Name] = true
+				sources[env.Name] = appendUnique(sources[env.Name], location)
+				if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+					ref := env.ValueFrom.SecretKeyRef
+					*refs = append(*refs, secretRef{envName: env.Name, secret: ref.Name, key: ref.Key, location: location})
 				}
 			}
 		}
 	}
 
-	sort.Strings(envVars)
-	return envVars, nil
+	return nil
+}
+
+// stripDeploymentPrefixes renames deployment variables that start with one of
+// prefixes (the first that matches wins), noting the original name in each location
+func stripDeploymentPrefixes(sources map[string][]string, prefixes []string) map[string][]string {
+	stripped := make(map[string][]string, len(sources))
+	for name, locations := range sources {
+		normalized := name
+		for _, prefix := range prefixes {
+			if prefix != "" && strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+				normalized = strings.TrimPrefix(name, prefix)
+				break
+			}
+		}
+		for _, location := range locations {
+			if normalized != name {
+				location = fmt.Sprintf("%s as %s", location, name)
+			}
+			stripped[normalized] = appendUnique(stripped[normalized], location)
+		}
+	}
+	return stripped
+}
+
+func appendUnique(list []string, item string) []string {
+	for _, existing := range list {
+		if existing == item {
+			return list
+		}
+	}
+	return append(list, item)
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func extractConfigVars(cfg *config.Config) []string {
 	var vars []string
-	for varName := range cfg.Mappings {
+	for varName := range cfg.OutputMappings() {
 		vars = append(vars, varName)
 	}
 	sort.Strings(vars)
 	return vars
 }
 
+// unmanagedSecretRefs returns the refs whose key is neither a variable the
+// config emits nor a Key Vault secret it reads
+func unmanagedSecretRefs(cfg *config.Config, refs []secretRef) []secretRef {
+	managed := make(map[string]bool)
+	for name, mapping := range cfg.OutputMappings() {
+		managed[name] = true
+		for _, env := range []config.Environment{config.EnvLocal, config.EnvDocker} {
+			if spec := cfg.ValueSpec(mapping, env); spec != nil && spec.IsKeyvaultSecret() {
+				managed[spec.Value] = true
+			}
+		}
+	}
+
+	var unmanaged []secretRef
+	for _, ref := range refs {
+		if !managed[ref.key] {
+			unmanaged = append(unmanaged, ref)
+		}
+	}
+	sort.Slice(unmanaged, func(i, j int) bool {
+		if unmanaged[i].envName != unmanaged[j].envName {
+			return unmanaged[i].envName < unmanaged[j].envName
+		}
+		return unmanaged[i].location < unmanaged[j].location
+	})
+	return unmanaged
+}
+
 func compareVars(configVars, deploymentVars []string) ComparisonResult {
 	configSet := make(map[string]bool)
 	deploymentSet := make(map[string]bool)
@@ -218,68 +389,88 @@ func compareVars(configVars, deploymentVars []string) ComparisonResult {
 }
 
 func displayComparisonResult(result ComparisonResult, deploymentFile string) {
-	ui.Info("🔍 Comparing configuration with deployment: %s", deploymentFile)
-	fmt.Println()
+	ui.Info("%sComparing configuration with deployment: %s", ui.Sym(ui.SymbolSearch), deploymentFile)
+	ui.Blank()
 
 	displaySummary(result)
 	displayMatchingVariables(result.Matching)
 	displayConfigOnlyVariables(result.InConfigOnly)
-	displayDeploymentOnlyVariables(result.InDeploymentOnly)
+	displayDeploymentOnlyVariables(result.InDeploymentOnly, result.Sources)
 	displayOverallStatus(result)
 }
 
 func displaySummary(result ComparisonResult) {
-	ui.Info("📊 Summary:")
-	fmt.Printf("  • Configuration variables: %d\n", len(result.ConfigVars))
-	fmt.Printf("  • Deployment variables:    %d\n", len(result.DeploymentVars))
-	fmt.Printf("  • Matching variables:      %d\n", len(result.Matching))
-	fmt.Println()
+	ui.Info("%sSummary:", ui.Sym(ui.SymbolSummary))
+	ui.Item(ui.SymbolBullet, "Configuration variables: %d", len(result.ConfigVars))
+	ui.Item(ui.SymbolBullet, "Deployment variables:    %d", len(result.DeploymentVars))
+	ui.Item(ui.SymbolBullet, "Matching variables:      %d", len(result.Matching))
+	ui.Blank()
 }
 
 func displayMatchingVariables(matching []string) {
 	if len(matching) > 0 {
-		ui.Success("✅ Variables present in both config and deployment (%d):", len(matching))
+		ui.Success("%sVariables present in both config and deployment (%d):", ui.Sym(ui.SymbolMatch), len(matching))
 		for _, v := range matching {
-			fmt.Printf("  ✓ %s\n", v)
+			ui.Item(ui.SymbolCheck, "%s", v)
 		}
-		fmt.Println()
+		ui.Blank()
 	}
 }
 
 func displayConfigOnlyVariables(configOnly []string) {
 	if len(configOnly) > 0 {
-		ui.Warn("⚠️  Variables in configuration but NOT used in deployment (%d):", len(configOnly))
+		ui.Warn("%sVariables in configuration but NOT used in deployment (%d):", ui.Sym(ui.SymbolWarning), len(configOnly))
 		for _, v := range configOnly {
-			fmt.Printf("  ⚠ %s\n", v)
+			ui.Item(ui.SymbolCross, "%s", v)
 		}
 		ui.Warn("These variables are configured but not used in your Kubernetes deployment.")
 		ui.Warn("Consider removing them from config or adding them to the deployment.")
-		fmt.Println()
+		ui.Blank()
 	}
 }
 
-func displayDeploymentOnlyVariables(deploymentOnly []string) {
+func displayDeploymentOnlyVariables(deploymentOnly []string, sources map[string][]string) {
 	if len(deploymentOnly) > 0 {
-		ui.Warn("⚠️  Variables in deployment but NOT defined in configuration (%d):", len(deploymentOnly))
+		ui.Warn("%sVariables in deployment but NOT defined in configuration (%d):", ui.Sym(ui.SymbolWarning), len(deploymentOnly))
 		for _, v := range deploymentOnly {
-			fmt.Printf("  ⚠ %s\n", v)
+			ui.Item(ui.SymbolCross, "%s", v)
+			if deploymentDir != "" || len(stripPrefixes) > 0 {
+				for _, src := range sources[v] {
+					ui.Print("      from %s", src)
+				}
+			}
 		}
 		ui.Warn("These variables are used in deployment but not managed by yeet.")
 		ui.Warn("Consider adding them to your env.config.json if they should be managed.")
-		fmt.Println()
+		ui.Blank()
+	}
+}
+
+func displaySecretRefs(unmanaged []secretRef, total int) {
+	if len(unmanaged) == 0 {
+		ui.Success("%sAll %d secret references point at keys managed by yeet.", ui.Sym(ui.SymbolMatch), total)
+		ui.Blank()
+		return
+	}
+	ui.Warn("%sSecret references to keys NOT managed by yeet (%d of %d):", ui.Sym(ui.SymbolWarning), len(unmanaged), total)
+	for _, ref := range unmanaged {
+		ui.Item(ui.SymbolCross, "%s -> secret %s, key %s", ref.envName, ref.secret, ref.key)
+		ui.Print("      from %s", ref.location)
 	}
+	ui.Warn("The referenced keys may have been renamed or removed from the config.")
+	ui.Blank()
 }
 
 func displayOverallStatus(result ComparisonResult) {
 	if len(result.InConfigOnly) == 0 && len(result.InDeploymentOnly) == 0 {
-		ui.Success("🎉 Perfect match! All variables are consistent between config and deployment.")
+		ui.Success("%sPerfect match! All variables are consistent between config and deployment.", ui.Sym(ui.SymbolCelebrate))
 	} else {
-		ui.Info("💡 Recommendations:")
+		ui.Info("%sRecommendations:", ui.Sym(ui.SymbolHint))
 		if len(result.InConfigOnly) > 0 {
-			fmt.Println("  • Review unused config variables and remove if not needed")
+			ui.Item(ui.SymbolBullet, "Review unused config variables and remove if not needed")
 		}
 		if len(result.InDeploymentOnly) > 0 {
-			fmt.Println("  • Add missing variables to env.config.json for centralized management")
+			ui.Item(ui.SymbolBullet, "Add missing variables to env.config.json for centralized management")
 		}
 	}
 }
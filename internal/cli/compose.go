@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+type composeBlockOptions struct {
+	env     string
+	service string
+}
+
+func newComposeBlockCmd() *cobra.Command {
+	opts := &composeBlockOptions{}
+	cmd := &cobra.Command{
+		Use:   "compose-block",
+		Short: "Print resolved values as a docker compose environment: block",
+		Long: `Resolve the values for an environment and print them as a docker compose
+"environment:" mapping, ready to paste under a service.
+
+Values are double-quoted and "$" is escaped as "$$" so compose does not
+interpolate them.`,
+		Example: `  yeet compose-block
+  yeet compose-block --env local
+  yeet compose-block --service api >> docker-compose.override.yml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runComposeBlock(cmd.Context(), opts, cmd.Flags().Changed("env"))
+		},
+	}
+	cmd.Flags().StringVarP(&opts.env, "env", "e", "docker", "Environment to resolve (local|docker)")
+	_ = cmd.RegisterFlagCompletionFunc("env", completeEnvironments)
+	cmd.Flags().StringVar(&opts.service, "service", "", "Nest the block under this service name")
+	return cmd
+}
+
+func runComposeBlock(ctx context.Context, opts *composeBlockOptions, envFlagSet bool) error {
+	cfg, vault, err := loadRunConfig()
+	if err != nil {
+		return err
+	}
+	env, err := parseEnvironment(defaultEnvironment(cfg, opts.env, envFlagSet))
+	if err != nil {
+		return err
+	}
+
+	prov := newProvider(cfg)
+	if err := ensureLoggedIn(ctx, prov); err != nil {
+		return err
+	}
+	if _, err := expandIncludes(ctx, prov, vault, cfg); err != nil {
+		return err
+	}
+
+	envVars, err := resolveEnvVars(ctx, cfg, vault, prov, env)
+	if err != nil {
+		return err
+	}
+
+	block := buildComposeEnvironment(envVars)
+	if opts.service != "" {
+		block = &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{scalarNode(opts.service), block}}
+	}
+
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	defer enc.Close()
+	return enc.Encode(block)
+}
+
+// buildComposeEnvironment returns an "environment:" mapping with sorted keys
+// and double-quoted, compose-escaped values
+func buildComposeEnvironment(envVars map[string]string) *yaml.Node {
+	keys := make([]string, 0, len(envVars))
+	for key := range envVars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	vars := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range keys {
+		value := &yaml.Node{
+			Kind:  yaml.ScalarNode,
+			Tag:   "!!str",
+			Style: yaml.DoubleQuotedStyle,
+			Value: strings.ReplaceAll(envVars[key], "$", "$$"),
+		}
+		vars.Content = append(vars.Content, scalarNode(key), value)
+	}
+
+	return &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{scalarNode("environment"), vars}}
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
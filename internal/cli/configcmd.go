@@ -0,0 +1,322 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+	"github.com/JayDubyaEey/yeet/internal/provider"
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect and edit the env configuration file",
+	}
+	cmd.AddCommand(newConfigSetVaultCmd())
+	cmd.AddCommand(newConfigRemoveCmd())
+	cmd.AddCommand(newConfigSortCmd())
+	return cmd
+}
+
+type setVaultOptions struct {
+	check bool
+}
+
+func newConfigSetVaultCmd() *cobra.Command {
+	opts := &setVaultOptions{}
+	cmd := &cobra.Command{
+		Use:   "set-vault NAME",
+		Short: "Change the Key Vault name in the config file",
+		Example: `  yeet config set-vault my-new-vault
+  yeet config set-vault my-new-vault --check`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigSetVault(cmd.Context(), args[0], opts)
+		},
+	}
+	cmd.Flags().BoolVar(&opts.check, "check", false, "Verify the new vault is reachable before saving")
+	return cmd
+}
+
+func runConfigSetVault(ctx context.Context, vault string, opts *setVaultOptions) error {
+	if err := rejectRemoteConfig(); err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if cfg.KeyVaultName == vault {
+		ui.Success("%s already uses vault %s", configSource(), vault)
+		return nil
+	}
+
+	if opts.check {
+		if err := checkVaultReachable(ctx, cfg, vault); err != nil {
+			return err
+		}
+	}
+
+	previous := cfg.KeyVaultName
+	cfg.KeyVaultName = vault
+	if err := config.Save(cfg, configPath); err != nil {
+		return err
+	}
+
+	ui.Success("updated %s: keyVaultName %s -> %s", configPath, previous, vault)
+	return nil
+}
+
+// checkVaultReachable confirms we are logged in and can list the vault
+func checkVaultReachable(ctx context.Context, cfg *config.Config, vault string) error {
+	prov := newProvider(cfg)
+	if err := ensureLoggedIn(ctx, prov); err != nil {
+		return err
+	}
+
+	if err := provider.CheckVaultAccess(ctx, prov, vault); err != nil {
+		return fmt.Errorf("vault %s is not reachable: %w", vault, err)
+	}
+	ui.Info("vault %s is reachable", vault)
+	return nil
+}
+
+type removeOptions struct {
+	allMatching string
+	andVault    bool
+}
+
+func newConfigRemoveCmd() *cobra.Command {
+	opts := &removeOptions{}
+	cmd := &cobra.Command{
+		Use:   "remove [KEY...]",
+		Short: "Remove mappings from the config file",
+		Example: `  yeet config remove OLD_API_KEY
+  yeet config remove --all-matching LEGACY_
+  yeet config remove OLD_API_KEY --and-vault`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && opts.allMatching == "" {
+				return fmt.Errorf("specify at least one KEY or --all-matching PREFIX")
+			}
+			return runConfigRemove(cmd.Context(), args, opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.allMatching, "all-matching", "", "Also remove every mapping whose key starts with this prefix")
+	cmd.Flags().BoolVar(&opts.andVault, "and-vault", false, "Also delete the Key Vault secrets no remaining mapping uses (asks for confirmation)")
+	return cmd
+}
+
+func runConfigRemove(ctx context.Context, keys []string, opts *removeOptions) error {
+	if err := rejectRemoteConfig(); err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	removed, err := selectMappingsToRemove(cfg, keys, opts.allMatching)
+	if err != nil {
+		return err
+	}
+	if len(removed) == 0 {
+		ui.Warn("no mappings start with %s", opts.allMatching)
+		return nil
+	}
+
+	// Work out orphaned secrets against the config as it will be saved
+	remaining := *cfg
+	remaining.Mappings = make(map[string]config.Mapping, len(cfg.Mappings))
+	for key, mapping := range cfg.Mappings {
+		remaining.Mappings[key] = mapping
+	}
+	for _, key := range removed {
+		delete(remaining.Mappings, key)
+	}
+	if len(remaining.Mappings) == 0 && len(remaining.Includes) == 0 {
+		return fmt.Errorf("refusing to remove every mapping: the config must keep at least one mapping or include")
+	}
+
+	vault := cfg.KeyVaultName
+	if vaultOverride != "" {
+		vault = vaultOverride
+	}
+
+	var orphaned []string
+	var deleter provider.Deleter
+	if opts.andVault {
+		orphaned = orphanedSecrets(cfg, &remaining, removed)
+		if len(orphaned) > 0 {
+			prov := newProvider(cfg)
+			var ok bool
+			if deleter, ok = prov.(provider.Deleter); !ok {
+				return fmt.Errorf("provider cannot delete secrets")
+			}
+			if err := ensureLoggedIn(ctx, prov); err != nil {
+				return err
+			}
+			prompt := fmt.Sprintf("Remove %d mapping(s) and delete %d secret(s) (%s) from vault %s?",
+				len(removed), len(orphaned), strings.Join(orphaned, ", "), vault)
+			if err := confirmOrAbort(prompt); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := config.Save(&remaining, configPath); err != nil {
+		return err
+	}
+	ui.Success("removed %d mapping(s) from %s:", len(removed), configPath)
+	for _, key := range removed {
+		ui.Item(ui.SymbolBullet, "%s", key)
+	}
+
+	if opts.andVault && len(orphaned) == 0 {
+		ui.Info("no secrets to delete: every removed secret is still used by another mapping")
+	}
+	failures := 0
+	for _, name := range orphaned {
+		if err := deleter.DeleteVaultSecret(ctx, vault, name); err != nil {
+			failures++
+			ui.Item(ui.SymbolCross, "%s: %v", name, err)
+			continue
+		}
+		ui.Item(ui.SymbolCheck, "deleted %s from %s", name, vault)
+	}
+	if failures > 0 {
+		return fmt.Errorf("failed to delete %d of %d secret(s)", failures, len(orphaned))
+	}
+	return nil
+}
+
+// selectMappingsToRemove resolves explicit keys and a prefix to the sorted
+// set of mapping keys to remove; every explicit key must exist
+func selectMappingsToRemove(cfg *config.Config, keys []string, prefix string) ([]string, error) {
+	selected := make(map[string]bool)
+	for _, key := range keys {
+		if _, ok := cfg.Mappings[key]; !ok {
+			return nil, fmt.Errorf("no mapping for %s in %s", key, configSource())
+		}
+		selected[key] = true
+	}
+	if prefix != "" {
+		for key := range cfg.Mappings {
+			if strings.HasPrefix(key, prefix) {
+				selected[key] = true
+			}
+		}
+	}
+
+	removed := make([]string, 0, len(selected))
+	for key := range selected {
+		removed = append(removed, key)
+	}
+	sort.Strings(removed)
+	return removed, nil
+}
+
+// orphanedSecrets returns the Key Vault secrets used by the removed mappings
+// that no mapping in remaining still references
+func orphanedSecrets(cfg, remaining *config.Config, removed []string) []string {
+	stillUsed := make(map[string]bool)
+	for _, mapping := range remaining.Mappings {
+		for _, name := range mappingSecretNames(mapping) {
+			stillUsed[name] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	var orphaned []string
+	for _, key := range removed {
+		for _, name := range mappingSecretNames(cfg.Mappings[key]) {
+			if !stillUsed[name] && !seen[name] {
+				seen[name] = true
+				orphaned = append(orphaned, name)
+			}
+		}
+	}
+	sort.Strings(orphaned)
+	return orphaned
+}
+
+// mappingSecretNames lists every Key Vault secret a mapping can refer to
+func mappingSecretNames(mapping config.Mapping) []string {
+	var names []string
+	for _, spec := range []*config.ValueSpec{mapping.Local, mapping.Docker} {
+		if spec.IsKeyvaultSecret() {
+			names = append(names, spec.Value)
+		}
+	}
+	if mapping.Type == config.ValueTypeKeyvault && mapping.Value != "" {
+		names = append(names, mapping.Value)
+	}
+	return names
+}
+
+type sortOptions struct {
+	check bool
+}
+
+func newConfigSortCmd() *cobra.Command {
+	opts := &sortOptions{}
+	cmd := &cobra.Command{
+		Use:   "sort",
+		Short: "Rewrite the config file in canonical order and formatting",
+		Long: `Rewrite the config file the way yeet saves it: two-space indentation, fields
+in a fixed order, mappings sorted by key and each mapping in its shortest form.
+Like gofmt for env.config.json, it keeps editor reordering out of diffs.`,
+		Example: `  yeet config sort
+  yeet config sort --check`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigSort(opts)
+		},
+	}
+	cmd.Flags().BoolVar(&opts.check, "check", false, "Don't write anything; exit non-zero if the file is not already canonical")
+	return cmd
+}
+
+func runConfigSort(opts *sortOptions) error {
+	if err := rejectRemoteConfig(); err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if len(cfg.Ignored) > 0 {
+		// Saving would silently drop them
+		return fmt.Errorf("%s has unknown fields (%s); fix or remove them before sorting", configPath, strings.Join(cfg.Ignored, ", "))
+	}
+
+	current, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", configPath, err)
+	}
+	canonical, err := config.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(current, canonical) {
+		ui.Success("%s is already canonical", configPath)
+		return nil
+	}
+
+	if opts.check {
+		return &exitCodeError{code: 1, msg: fmt.Sprintf("%s is not canonical (run: yeet config sort)", configPath)}
+	}
+	if err := config.Save(cfg, configPath); err != nil {
+		return err
+	}
+	ui.Success("sorted %s", configPath)
+	return nil
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+var (
+	strictConfig bool
+	// ignoredReported stops commands that load the config more than once
+	// from repeating the warning
+	ignoredReported bool
+)
+
+// loadConfig loads the config at configPath and reports fields in it that
+// the config format does not define
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := reportIgnoredFields(cfg.Ignored); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// reportIgnoredFields warns about unknown config fields, which are usually
+// typos that would otherwise surface later as missing values, or fails on
+// them with --strict-config
+func reportIgnoredFields(fields []string) error {
+	if len(fields) == 0 || ignoredReported {
+		return nil
+	}
+	ignoredReported = true
+
+	if strictConfig {
+		ui.Error("%d unknown fields in %s:", len(fields), configSource())
+		for _, field := range fields {
+			ui.Error("  - %s", field)
+		}
+		return fmt.Errorf("%s has %d unknown fields (--strict-config)", configSource(), len(fields))
+	}
+	ui.WarnStderr("%d unknown fields in %s are ignored (check for typos):", len(fields), configSource())
+	for _, field := range fields {
+		ui.WarnStderr("  - %s", field)
+	}
+	return nil
+}
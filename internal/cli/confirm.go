@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// errAborted is returned when the user declines a confirmation prompt
+var errAborted = errors.New("aborted")
+
+// confirmOrAbort asks the user to confirm a mutating operation. It returns nil
+// when --yes was given or the user answers yes, and an error otherwise.
+// Without a terminal there is nobody to ask, so --yes is required.
+func confirmOrAbort(prompt string) error {
+	if assumeYes {
+		return nil
+	}
+	if !isTerminal(os.Stdin) {
+		return fmt.Errorf("%s: refusing to continue without confirmation in a non-interactive session (pass --yes)", prompt)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return errAborted
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	default:
+		return errAborted
+	}
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+type diffEnvsOptions struct {
+	showSecrets bool
+}
+
+func newDiffEnvsCmd() *cobra.Command {
+	opts := &diffEnvsOptions{}
+	cmd := &cobra.Command{
+		Use:   "diff-envs ENV ENV",
+		Short: "Compare the resolved values of two environments",
+		Long: `Resolve both environments and report, per key, whether the value is the
+same, differs, or is only set in one of them. Key Vault values are masked
+unless --show-secrets is given.`,
+		Example: `  yeet diff-envs local docker
+  yeet diff-envs local docker --show-secrets`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDiffEnvs(cmd.Context(), args[0], args[1], opts)
+		},
+	}
+	cmd.Flags().BoolVar(&opts.showSecrets, "show-secrets", false, "Print differing Key Vault values instead of masking them")
+	return cmd
+}
+
+func runDiffEnvs(ctx context.Context, leftName, rightName string, opts *diffEnvsOptions) error {
+	left, err := parseEnvironment(leftName)
+	if err != nil {
+		return err
+	}
+	right, err := parseEnvironment(rightName)
+	if err != nil {
+		return err
+	}
+	if left == right {
+		return fmt.Errorf("choose two different environments")
+	}
+
+	cfg, vault, err := loadRunConfig()
+	if err != nil {
+		return err
+	}
+
+	prov := newProvider(cfg)
+	if err := ensureLoggedIn(ctx, prov); err != nil {
+		return err
+	}
+	if _, err := expandIncludes(ctx, prov, vault, cfg); err != nil {
+		return err
+	}
+
+	leftVars, err := resolveEnvVars(ctx, cfg, vault, prov, left)
+	if err != nil {
+		return err
+	}
+	rightVars, err := resolveEnvVars(ctx, cfg, vault, prov, right)
+	if err != nil {
+		return err
+	}
+
+	leftShown, rightShown := leftVars, rightVars
+	if !opts.showSecrets {
+		leftShown = maskedCopy(cfg, left, leftVars)
+		rightShown = maskedCopy(cfg, right, rightVars)
+	}
+
+	keys := make(map[string]bool, len(leftVars)+len(rightVars))
+	for key := range leftVars {
+		keys[key] = true
+	}
+	for key := range rightVars {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var same, differ, only int
+	for _, key := range sorted {
+		l, inLeft := leftVars[key]
+		r, inRight := rightVars[key]
+		switch {
+		case !inRight:
+			only++
+			ui.Item(ui.SymbolWarning, "%s only in %s", key, left)
+		case !inLeft:
+			only++
+			ui.Item(ui.SymbolWarning, "%s only in %s", key, right)
+		case l == r:
+			same++
+			ui.Item(ui.SymbolCheck, "%s same", key)
+		default:
+			differ++
+			ui.Item(ui.SymbolCross, "%s differs: %s=%q %s=%q", key, left, leftShown[key], right, rightShown[key])
+		}
+	}
+
+	ui.Blank()
+	ui.Success("%d same, %d differ, %d in one environment only", same, differ, only)
+	return nil
+}
+
+// maskedCopy returns envVars with the values env resolves from Key Vault masked
+func maskedCopy(cfg *config.Config, env config.Environment, envVars map[string]string) map[string]string {
+	masked := make(map[string]string, len(envVars))
+	for key, value := range envVars {
+		masked[key] = value
+	}
+	maskKeyvaultValues(cfg, env, masked)
+	return masked
+}
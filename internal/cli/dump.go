@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+	"github.com/JayDubyaEey/yeet/internal/envwriter"
+)
+
+const (
+	dumpFormatJSON   = "json"
+	dumpFormatDotenv = "dotenv"
+
+	// maskedValue replaces Key Vault values unless --show-secrets is given; it
+	// has a fixed length so it reveals nothing about the value
+	maskedValue = "********"
+)
+
+type dumpOptions struct {
+	env         string
+	format      string
+	showSecrets bool
+	print0      bool
+}
+
+func newDumpCmd() *cobra.Command {
+	opts := &dumpOptions{}
+	cmd := &cobra.Command{
+		Use:   "dump",
+		Short: "Print the resolved environment without writing files",
+		Long: `Resolve every value for an environment and print the result to stdout as
+JSON or dotenv, sorted by key. Values from Key Vault are masked unless
+--show-secrets is given; literal values are always shown.
+
+--print0 writes each variable as KEY, NUL, VALUE, NUL with no quoting or
+escaping, sorted by key, so values containing newlines or spaces can be split
+unambiguously (e.g. with xargs -0 or read -d '').`,
+		Example: `  yeet dump
+  yeet dump --env docker --format dotenv
+  diff <(yeet dump -e local) <(yeet dump -e docker)
+  yeet dump --print0 --show-secrets | xargs -0 -n2 printf '%s has %s\n'`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.print0 && cmd.Flags().Changed("format") {
+				return fmt.Errorf("--print0 cannot be used with --format")
+			}
+			return runDump(cmd.Context(), opts, cmd.Flags().Changed("env"))
+		},
+	}
+	cmd.Flags().StringVarP(&opts.env, "env", "e", "local", "Environment to resolve (local|docker)")
+	_ = cmd.RegisterFlagCompletionFunc("env", completeEnvironments)
+	cmd.Flags().StringVar(&opts.format, "format", dumpFormatJSON, "Output format ("+dumpFormatJSON+"|"+dumpFormatDotenv+")")
+	cmd.Flags().BoolVar(&opts.showSecrets, "show-secrets", false, "Print Key Vault values instead of masking them")
+	cmd.Flags().BoolVar(&opts.print0, "print0", false, "Print KEY\\0VALUE\\0 pairs with no quoting, for values with newlines or spaces")
+	return cmd
+}
+
+func runDump(ctx context.Context, opts *dumpOptions, envFlagSet bool) error {
+	if opts.format != dumpFormatJSON && opts.format != dumpFormatDotenv {
+		return fmt.Errorf("invalid --format %q: must be %q or %q", opts.format, dumpFormatJSON, dumpFormatDotenv)
+	}
+
+	cfg, vault, err := loadRunConfig()
+	if err != nil {
+		return err
+	}
+	env, err := parseEnvironment(defaultEnvironment(cfg, opts.env, envFlagSet))
+	if err != nil {
+		return err
+	}
+
+	prov := newProvider(cfg)
+	if err := ensureLoggedIn(ctx, prov); err != nil {
+		return err
+	}
+	if _, err := expandIncludes(ctx, prov, vault, cfg); err != nil {
+		return err
+	}
+
+	envVars, err := resolveEnvVars(ctx, cfg, vault, prov, env)
+	if err != nil {
+		return err
+	}
+	if !opts.showSecrets {
+		maskKeyvaultValues(cfg, env, envVars)
+	}
+
+	if opts.format == dumpFormatJSON && !opts.print0 {
+		return outputJSON(envVars)
+	}
+
+	keys := make([]string, 0, len(envVars))
+	for key := range envVars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if opts.print0 {
+		for _, key := range keys {
+			fmt.Printf("%s\x00%s\x00", key, envVars[key])
+		}
+		return nil
+	}
+	for _, key := range keys {
+		fmt.Println(envwriter.FormatLine(key, envVars[key]))
+	}
+	return nil
+}
+
+// maskKeyvaultValues replaces every value env resolves from Key Vault
+func maskKeyvaultValues(cfg *config.Config, env config.Environment, envVars map[string]string) {
+	for key, mapping := range cfg.Mappings {
+		spec := cfg.ValueSpec(mapping, env)
+		if !spec.IsKeyvaultSecret() {
+			continue
+		}
+		if _, ok := envVars[mapping.OutputName(key)]; ok {
+			envVars[mapping.OutputName(key)] = maskedValue
+		}
+	}
+}
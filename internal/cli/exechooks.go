@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// shellCommand returns a command that runs command through the platform shell
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// runExecHook runs a --preexec or --postexec command with the same
+// environment, working directory and stdio as the main command
+func runExecHook(ctx context.Context, flag, command string, envVars map[string]string, stdout, stderr io.Writer) error {
+	cmd := shellCommand(ctx, command)
+	cmd.Env = parentEnv()
+	for key, value := range envVars {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+	}
+	cmd.Dir = workDir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %q failed: %w", flag, command, err)
+	}
+	return nil
+}
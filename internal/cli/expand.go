@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"os"
+	"strings"
+)
+
+// expandValue replaces ${VAR} and ${VAR:-default} references in value, looking
+// names up in resolved first and then in the process environment. Unknown
+// references without a default expand to an empty string, matching dotenv tooling.
+func expandValue(value string, resolved map[string]string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(value, "${")
+		if start < 0 {
+			b.WriteString(value)
+			return b.String()
+		}
+		end := strings.Index(value[start:], "}")
+		if end < 0 {
+			b.WriteString(value)
+			return b.String()
+		}
+		end += start
+
+		b.WriteString(value[:start])
+		b.WriteString(lookupReference(value[start+2:end], resolved))
+		value = value[end+1:]
+	}
+}
+
+// lookupReference resolves the inside of a ${...} reference
+func lookupReference(ref string, resolved map[string]string) string {
+	name, def, hasDefault := strings.Cut(ref, ":-")
+
+	val, ok := resolved[name]
+	if !ok {
+		val, ok = os.LookupEnv(name)
+	}
+	if (!ok || val == "") && hasDefault {
+		return def
+	}
+	return val
+}
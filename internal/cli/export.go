@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+// shellNameRegex is what a POSIX shell accepts as a variable name
+var shellNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type exportOptions struct {
+	env      string
+	fromFile string
+	section  string
+}
+
+func newExportCmd() *cobra.Command {
+	opts := &exportOptions{}
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Print the resolved environment as shell export statements",
+		Long: `Resolve every value for an environment and print it as POSIX shell
+"export KEY='value'" lines for eval, e.g. from direnv (see 'yeet hook').
+Only the export lines go to stdout; problems are reported on stderr.`,
+		Example: `  eval "$(yeet export)"
+  eval "$(yeet export --env docker)"
+  eval "$(yeet export --from-file env.combined --section local)"`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExport(cmd.Context(), opts, cmd.Flags().Changed("env"))
+		},
+	}
+	cmd.Flags().StringVarP(&opts.env, "env", "e", "local", "Environment to resolve (local|docker)")
+	_ = cmd.RegisterFlagCompletionFunc("env", completeEnvironments)
+	cmd.Flags().StringVar(&opts.fromFile, "from-file", "", "Export values from this env file instead of resolving them from the vault")
+	cmd.Flags().StringVar(&opts.section, "section", "", "With --from-file, read only this section of a file written by 'fetch --combine'")
+	return cmd
+}
+
+func runExport(ctx context.Context, opts *exportOptions, envFlagSet bool) error {
+	// stdout is for eval, so only errors (on stderr) may be printed
+	ui.SetMuted(true)
+	defer ui.SetMuted(false)
+
+	if opts.section != "" && opts.fromFile == "" {
+		return fmt.Errorf("--section requires --from-file")
+	}
+	if opts.fromFile != "" {
+		envVars, err := readExportFile(opts.fromFile, opts.section)
+		if err != nil {
+			return err
+		}
+		printExports(envVars)
+		return nil
+	}
+
+	cfg, vault, err := loadRunConfig()
+	if err != nil {
+		return err
+	}
+	env, err := parseEnvironment(defaultEnvironment(cfg, opts.env, envFlagSet))
+	if err != nil {
+		return err
+	}
+
+	prov := newProvider(cfg)
+	if err := ensureLoggedIn(ctx, prov); err != nil {
+		return err
+	}
+	if _, err := expandIncludes(ctx, prov, vault, cfg); err != nil {
+		return err
+	}
+
+	envVars, err := resolveEnvVars(ctx, cfg, vault, prov, env)
+	if err != nil {
+		return err
+	}
+	printExports(envVars)
+	return nil
+}
+
+// readExportFile reads --from-file, or one section of it
+func readExportFile(path, section string) (map[string]string, error) {
+	if section == "" {
+		values, _, err := loadEnvOverrides(path, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		return values, nil
+	}
+	values, _, err := loadEnvSection(path, section, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// printExports prints envVars as sorted export statements
+func printExports(envVars map[string]string) {
+	keys := make([]string, 0, len(envVars))
+	for key := range envVars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !shellNameRegex.MatchString(key) {
+			ui.Error("skipping %s: not a valid shell variable name", key)
+			continue
+		}
+		fmt.Printf("export %s=%s\n", key, shellQuote(envVars[key]))
+	}
+}
+
+// shellQuote single-quotes value for a POSIX shell, so nothing in it is expanded
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
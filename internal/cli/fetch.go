@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,25 +16,116 @@ import (
 
 	"github.com/JayDubyaEey/yeet/internal/config"
 	"github.com/JayDubyaEey/yeet/internal/envwriter"
-	"github.com/JayDubyaEey/yeet/internal/provider/azcli"
+	"github.com/JayDubyaEey/yeet/internal/provider"
 	"github.com/JayDubyaEey/yeet/internal/ui"
 )
 
+type fetchOptions struct {
+	metricsFile       string
+	secretNotFound    string
+	mode              string
+	owner             string
+	parallelFiles     bool
+	diffExit          bool
+	annotateUnmanaged bool
+	onlyEnvSpecific   bool
+	summaryOnly       bool
+	filesDir          string
+	jobsFromVault     bool
+	raw               bool
+	bundleOut         string
+	loop              bool
+	interval          time.Duration
+	sinceFile         bool
+	force             bool
+	combine           string
+	retryMissing      int
+	retryDelay        time.Duration
+	preserveLayout    bool
+	verifyAfter       bool
+	report            string
+	keysUpper         bool
+	noLock            bool
+	lockTimeout       time.Duration
+	maxSecretSize     int
+	deterministicTime bool
+	oversize          string
+	warnOnPlaceholder bool
+	splitByPrefix     bool
+	stripPrefix       bool
+}
+
+const (
+	notFoundFail = "fail"
+	notFoundSkip = "skip"
+
+	oversizeFail = "fail"
+	oversizeWarn = "warn"
+
+	// defaultMaxSecretSize is Key Vault's own limit on a secret value
+	defaultMaxSecretSize = 25 * 1024
+)
+
 func newFetchCmd() *cobra.Command {
+	opts := &fetchOptions{}
 	cmd := &cobra.Command{
-		Use:   "fetch",
-		Short: "Fetch secrets and write .env and docker.env",
+		Use:     "fetch",
+		Aliases: []string{"refresh"},
+		Short:   "Fetch secrets and write .env and docker.env",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runFetch(cmd.Context())
+			if opts.loop {
+				if opts.sinceFile {
+					return fmt.Errorf("--since-file cannot be used with --loop")
+				}
+				return runFetchLoop(cmd.Context(), opts, opts.interval)
+			}
+			return runFetch(cmd.Context(), opts)
 		},
 	}
+	cmd.Flags().BoolVar(&opts.loop, "loop", false, "Keep running and re-fetch every --interval until stopped")
+	cmd.Flags().DurationVar(&opts.interval, "interval", 15*time.Minute, "Time between fetches with --loop")
+	cmd.Flags().StringVar(&opts.secretNotFound, "secret-not-found", notFoundFail, "What to do when a secret is missing from the vault (fail|skip)")
+	cmd.Flags().IntVar(&opts.maxSecretSize, "max-secret-size", defaultMaxSecretSize, "Largest Key Vault value in bytes that may be written to an env file (0 means no limit)")
+	cmd.Flags().StringVar(&opts.oversize, "oversize", oversizeFail, "What to do when a value exceeds --max-secret-size (fail|warn)")
+	cmd.Flags().IntVar(&opts.retryMissing, "retry-missing", 0, "Refetch secrets that were not found up to this many times before failing")
+	cmd.Flags().DurationVar(&opts.retryDelay, "retry-delay", 10*time.Second, "Time to wait before each --retry-missing pass")
+	cmd.Flags().BoolVar(&opts.sinceFile, "since-file", false, "Do nothing if .env and docker.env are newer than the config file")
+	cmd.Flags().BoolVar(&opts.force, "force", false, "Fetch even when --since-file finds the env files up to date")
+	cmd.Flags().BoolVar(&opts.jobsFromVault, "jobs-from-vault", false, "Report vault secrets not referenced by the config instead of writing files")
+	cmd.Flags().BoolVar(&opts.raw, "raw", false, "Output the --jobs-from-vault report, or the missing secrets report, as JSON")
+	cmd.Flags().StringVar(&opts.bundleOut, "bundle-out", "", "Also write the resolved values to this encrypted bundle (passphrase from --passphrase-env)")
+	cmd.Flags().StringVar(&bundlePassphraseEnv, "passphrase-env", defaultPassphraseEnv, "Environment variable holding the bundle passphrase")
+	cmd.Flags().StringVar(&opts.filesDir, "files-dir", ".secrets", "Directory for decoded binary secrets")
+	cmd.Flags().BoolVar(&opts.summaryOnly, "summary-only", false, "Suppress per-key output and print a single summary line")
+	cmd.Flags().BoolVar(&opts.diffExit, "diff-exit", false, "Exit with code 2 if any value changed (files are still written)")
+	cmd.Flags().BoolVar(&opts.keysUpper, "dotenv-keys-upper", false, "Write every key in SHOUTING_SNAKE_CASE (app-url becomes APP_URL), failing if two keys would collide")
+	cmd.Flags().BoolVar(&opts.onlyEnvSpecific, "only-env-specific", false, "Only write values set explicitly for local or docker, skipping the global fallback (docker.env still falls back to local values)")
+	cmd.Flags().BoolVar(&opts.annotateUnmanaged, "annotate-unmanaged", false, "Write a comment above retained keys that are not defined in the config")
+	cmd.Flags().StringVar(&opts.combine, "combine", "", "Write both environments to this one file, in # [local] and # [docker] sections, instead of .env and docker.env")
+	cmd.Flags().BoolVar(&opts.warnOnPlaceholder, "warn-on-placeholder", false, "Warn about literal values that look like unreplaced placeholders (your-..., CHANGEME, xxx, TODO)")
+	cmd.Flags().BoolVar(&opts.splitByPrefix, "split-by-prefix", false, "Write keys to one file per leading PREFIX_ segment (.env.api, docker.env.api); keys without one stay in .env and docker.env")
+	cmd.Flags().BoolVar(&opts.stripPrefix, "strip-prefix", false, "With --split-by-prefix, remove the prefix from the keys written to each group's file")
+	cmd.Flags().BoolVar(&opts.preserveLayout, "preserve-layout", false, "Update values in place in existing env files, keeping their comments, blank lines and key order")
+	cmd.Flags().BoolVar(&opts.verifyAfter, "verify-after", false, "Re-read each written file and restore the previous one if any value does not read back exactly")
+	cmd.Flags().BoolVar(&opts.noLock, "no-lock", false, "Don't take the "+fetchLockFile+" lock that stops concurrent fetches in this directory from racing")
+	cmd.Flags().DurationVar(&opts.lockTimeout, "lock-timeout", 30*time.Second, "How long to wait for another fetch in this directory to finish")
+	cmd.Flags().BoolVar(&opts.deterministicTime, "deterministic-time", false, "Leave the timestamp out of the file header so unchanged inputs give byte-identical files")
+	cmd.Flags().BoolVar(&opts.parallelFiles, "parallel-files", false, "Replace .env and docker.env together, or leave both unchanged on failure")
+	cmd.Flags().StringVar(&opts.mode, "mode", "0600", "File mode for generated env files (octal)")
+	cmd.Flags().StringVar(&opts.owner, "owner", "", "Owner for generated env files (user[:group])")
+	cmd.Flags().StringVar(&opts.report, "report", "", "Write a JSON report of the run (keys written, changed, missing; never values) to this path, even if the fetch fails")
+	cmd.Flags().StringVar(&opts.metricsFile, "metrics-file", "", "Write Prometheus textfile-collector metrics to this path after a successful run")
 	return cmd
 }
 
 type fetchContext struct {
-	cfg   *config.Config
-	vault string
-	prov  *azcli.Provider
+	cfg       *config.Config
+	vault     string
+	prov      provider.Provider
+	opts      *fetchOptions
+	trace     *fetchTracer
+	report    *fetchReport
+	writeOpts envwriter.WriteOptions
 }
 
 type secretResult struct {
@@ -40,31 +134,181 @@ type secretResult struct {
 	environment config.Environment
 }
 
-func runFetch(ctx context.Context) error {
-	fctx, err := prepareFetch()
+func runFetch(ctx context.Context, opts *fetchOptions) (err error) {
+	start := time.Now()
+	report := newFetchReport(opts.report)
+	defer func() {
+		if reportErr := report.write(start, err); reportErr != nil && err == nil {
+			err = reportErr
+		}
+	}()
+
+	if opts.secretNotFound != notFoundFail && opts.secretNotFound != notFoundSkip {
+		return fmt.Errorf("invalid --secret-not-found %q: must be %q or %q", opts.secretNotFound, notFoundFail, notFoundSkip)
+	}
+	if opts.preserveLayout && opts.combine != "" {
+		return fmt.Errorf("--preserve-layout cannot be used with --combine")
+	}
+	if opts.splitByPrefix && (opts.combine != "" || opts.parallelFiles) {
+		return fmt.Errorf("--split-by-prefix cannot be used with --combine or --parallel-files")
+	}
+	if opts.stripPrefix && !opts.splitByPrefix {
+		return fmt.Errorf("--strip-prefix requires --split-by-prefix")
+	}
+	if opts.oversize != oversizeFail && opts.oversize != oversizeWarn {
+		return fmt.Errorf("invalid --oversize %q: must be %q or %q", opts.oversize, oversizeFail, oversizeWarn)
+	}
+	if opts.maxSecretSize < 0 {
+		return fmt.Errorf("--max-secret-size cannot be negative")
+	}
+	if opts.retryMissing < 0 {
+		return fmt.Errorf("--retry-missing cannot be negative")
+	}
+
+	if opts.summaryOnly {
+		ui.SetMuted(true)
+		defer ui.SetMuted(false)
+	}
+
+	if !opts.noLock {
+		release, err := acquireFetchLock(ctx, fetchLockFile, opts.lockTimeout)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	if opts.sinceFile && !opts.force {
+		if outputs := outputFiles(opts); envFilesUpToDate(outputs) {
+			ui.Success("%s newer than %s, skipping fetch (use --force to fetch anyway)", describeOutputs(outputs), configSource())
+			return nil
+		}
+	}
+
+	fctx, err := prepareFetch(opts)
 	if err != nil {
 		return err
 	}
+	fctx.report = report
+	report.setVault(fctx.vault)
+	report.setProvider(activeProvider(fctx.cfg))
+
+	if err := ensureLoggedIn(ctx, fctx.prov); err != nil {
+		return err
+	}
+
+	if _, err := expandIncludes(ctx, fctx.prov, fctx.vault, fctx.cfg); err != nil {
+		return err
+	}
+
+	if opts.onlyEnvSpecific {
+		omitGlobalFallbacks(fctx.cfg)
+	}
+
+	if opts.keysUpper {
+		if err := upperCaseEnvNames(fctx.cfg); err != nil {
+			return err
+		}
+	}
+
+	if opts.warnOnPlaceholder {
+		warnPlaceholderLiterals(fctx.cfg)
+	}
 
-	if err := fctx.prov.EnsureLoggedIn(ctx); err != nil {
-		return fmt.Errorf("not logged in to Azure CLI: %w (run: yeet login)", err)
+	if opts.jobsFromVault {
+		unreferenced, err := findUnreferencedSecrets(ctx, fctx.prov, fctx.vault, fctx.cfg)
+		if err != nil {
+			return err
+		}
+		return reportUnreferencedSecrets(unreferenced, fctx.vault, opts.raw)
 	}
 
 	results, missing, err := fetchSecrets(ctx, fctx)
 	if err != nil {
 		return err
 	}
+	report.setMissing(missing)
 
 	if len(missing) > 0 {
-		return reportMissingSecrets(missing, fctx.vault)
+		if opts.secretNotFound == notFoundFail {
+			return reportMissingSecrets(missing, fctx.vault, opts.raw)
+		}
+		warnSkippedSecrets(missing, fctx.vault)
+	}
+
+	if violations := checkValueRules(results, fctx.cfg); len(violations) > 0 {
+		return reportRuleViolations(violations)
+	}
+
+	if oversized := checkSecretSizes(results, fctx.cfg, opts.maxSecretSize); len(oversized) > 0 {
+		if opts.oversize == oversizeFail {
+			ui.Error("%d values exceed --max-secret-size %d bytes (store large values as binary files instead):", len(oversized), opts.maxSecretSize)
+			for _, entry := range oversized {
+				ui.Error("  - %s", entry)
+			}
+			return fmt.Errorf("%d values exceed --max-secret-size", len(oversized))
+		}
+		ui.Warn("%d values exceed --max-secret-size %d bytes:", len(oversized), opts.maxSecretSize)
+		for _, entry := range oversized {
+			ui.Warn("  - %s", entry)
+		}
+	}
+
+	if err := materializeBinarySecrets(results, fctx.cfg, opts.filesDir); err != nil {
+		return err
 	}
 
 	envMap, dockerMap := buildEnvMaps(results, fctx.cfg)
-	return writeEnvFiles(envMap, dockerMap, fctx)
+	if opts.bundleOut != "" {
+		if err := writeBundle(opts.bundleOut, fctx.vault, envMap, dockerMap); err != nil {
+			return err
+		}
+	}
+
+	written, err := writeEnvFiles(envMap, dockerMap, fctx)
+	if err != nil {
+		return err
+	}
+	changed := written.changed
+
+	if opts.summaryOnly {
+		ui.SetMuted(false)
+		if opts.combine != "" {
+			ui.Success("wrote %s (%d local, %d docker keys), %d unmapped retained, %d changed",
+				opts.combine, written.envKeys, written.dockerKeys, written.unmapped, written.changed)
+		} else if opts.splitByPrefix {
+			ui.Success("wrote %d files (%d local, %d docker keys), %d unmapped retained, %d changed",
+				written.files, written.envKeys, written.dockerKeys, written.unmapped, written.changed)
+		} else {
+			ui.Success("wrote .env (%d keys), docker.env (%d keys), %d unmapped retained, %d changed",
+				written.envKeys, written.dockerKeys, written.unmapped, written.changed)
+		}
+		ui.SetMuted(true)
+	}
+
+	if opts.metricsFile != "" {
+		m := fetchMetrics{
+			duration:       time.Since(start),
+			secretsTotal:   len(collectSecretsToFetch(fctx.cfg)),
+			secretsChanged: changed,
+			lastSuccess:    time.Now(),
+		}
+		if err := writeMetricsFile(opts.metricsFile, m); err != nil {
+			return err
+		}
+		ui.Info("wrote metrics to %s", opts.metricsFile)
+	}
+
+	if opts.diffExit && changed > 0 {
+		ui.Warn("%d values changed", changed)
+		return &exitCodeError{code: 2}
+	}
+
+	return nil
 }
 
-func prepareFetch() (*fetchContext, error) {
-	cfg, err := config.Load(configPath)
+func prepareFetch(opts *fetchOptions) (*fetchContext, error) {
+	cfg, err := loadConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -74,53 +318,174 @@ func prepareFetch() (*fetchContext, error) {
 		vault = vaultOverride
 	}
 
+	writeOpts, err := parseWriteOptions(opts.mode, opts.owner)
+	if err != nil {
+		return nil, err
+	}
+	writeOpts.Preserve = opts.preserveLayout
+	writeOpts.Verify = opts.verifyAfter
+
 	return &fetchContext{
-		cfg:   cfg,
-		vault: vault,
-		prov:  azcli.NewDefault(),
+		cfg:       cfg,
+		vault:     vault,
+		prov:      newProvider(cfg),
+		opts:      opts,
+		trace:     newFetchTracer(),
+		writeOpts: writeOpts,
 	}, nil
 }
 
-func fetchSecrets(ctx context.Context, fctx *fetchContext) ([]secretResult, []string, error) {
+// upperCaseEnvNames sets every mapping's emitted name to its SHOUTING_SNAKE_CASE
+// form, rejecting mappings that would end up with the same name
+func upperCaseEnvNames(cfg *config.Config) error {
+	keys := make([]string, 0, len(cfg.Mappings))
+	for key := range cfg.Mappings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	emitted := make(map[string]string, len(keys))
+	for _, key := range keys {
+		mapping := cfg.Mappings[key]
+		name := config.ToShoutingSnakeCase(mapping.OutputName(key))
+		if other, ok := emitted[name]; ok {
+			return fmt.Errorf("--dotenv-keys-upper: %s and %s would both be written as %s", other, key, name)
+		}
+		emitted[name] = key
+		if name != mapping.OutputName(key) {
+			ui.Info("writing %s as %s", mapping.OutputName(key), name)
+			mapping.EnvName = name
+			cfg.Mappings[key] = mapping
+		}
+	}
+	return nil
+}
+
+// omitGlobalFallbacks clears every mapping's global type/value so only values
+// set explicitly for an environment are written. docker.env still falls back
+// to an explicit local value, as it does for mappings without a global value.
+func omitGlobalFallbacks(cfg *config.Config) {
+	_, customDocker := cfg.ResolveOrder[config.EnvDocker]
+	var omitted []string
+	for key, mapping := range cfg.Mappings {
+		if _, source := cfg.ValueSpecSource(mapping, config.EnvLocal); source == config.ResolveGlobal {
+			omitted = append(omitted, fmt.Sprintf("%s (%s)", key, config.EnvLocal))
+		}
+		if _, source := cfg.ValueSpecSource(mapping, config.EnvDocker); source == config.ResolveGlobal {
+			if customDocker || mapping.Local == nil {
+				omitted = append(omitted, fmt.Sprintf("%s (%s)", key, config.EnvDocker))
+			}
+		}
+		mapping.Type, mapping.Value, mapping.Binary = "", "", false
+		cfg.Mappings[key] = mapping
+	}
+
+	sort.Strings(omitted)
+	ui.Info("omitting %d values that only come from a global fallback", len(omitted))
+	for _, entry := range omitted {
+		ui.Info("  - %s", entry)
+	}
+}
+
+func fetchSecrets(ctx context.Context, fctx *fetchContext) ([]secretResult, []missingValue, error) {
 	// We need to fetch secrets for both environments
 	localSecrets := make(map[string]string) // secret name -> value cache
-	missing := make([]string, 0)
+	missing := make([]missingValue, 0)
 
 	g, gctx := errgroup.WithContext(ctx)
-	sem := make(chan struct{}, 6)
+	sem := make(chan struct{}, concurrency)
 	var mu sync.Mutex
 
 	// Collect all unique Key Vault secrets we need to fetch
 	secretsToFetch := collectSecretsToFetch(fctx.cfg)
 
 	// Fetch all required secrets concurrently
-	if err := fetchAllSecrets(gctx, g, sem, fctx, secretsToFetch, localSecrets, &missing, &mu); err != nil {
+	err := fetchAllSecrets(gctx, g, sem, fctx, secretsToFetch, localSecrets, &missing, &mu)
+	if err == nil {
+		err = retryMissingSecrets(ctx, fctx, secretsToFetch, localSecrets, &missing)
+	}
+	fctx.trace.report()
+	if err != nil {
 		return nil, nil, err
 	}
 
 	// Build results for each environment variable
-	results, missingMappings := buildResultsFromSecrets(fctx.cfg, localSecrets)
+	results, missingMappings, err := buildResultsFromSecrets(fctx.cfg, localSecrets)
+	if err != nil {
+		return nil, nil, err
+	}
 	missing = append(missing, missingMappings...)
 
 	return results, missing, nil
 }
 
+// checkSecretSizes returns a "KEY (env): N bytes from SECRET" entry for every
+// Key Vault value over limit bytes. Binary values are written to files, not
+// the env file, so they are not checked.
+func checkSecretSizes(results []secretResult, cfg *config.Config, limit int) []string {
+	if limit == 0 {
+		return nil
+	}
+	var oversized []string
+	for _, r := range results {
+		spec := cfg.ValueSpec(cfg.Mappings[r.key], r.environment)
+		if !spec.IsKeyvaultSecret() || spec.Binary || len(r.value) <= limit {
+			continue
+		}
+		oversized = append(oversized, fmt.Sprintf("%s (%s): %d bytes from %s", r.key, r.environment, len(r.value), spec.Value))
+	}
+	sort.Strings(oversized)
+	return oversized
+}
+
+// retryMissingSecrets refetches only the secrets that were not found, up to
+// --retry-missing times, for pipelines where creating a secret races the fetch
+func retryMissingSecrets(ctx context.Context, fctx *fetchContext, secretsToFetch map[string]bool, cache map[string]string, missing *[]missingValue) error {
+	for attempt := 1; attempt <= fctx.opts.retryMissing; attempt++ {
+		pending := make(map[string]bool)
+		for secretName := range secretsToFetch {
+			if _, ok := cache[secretName]; !ok {
+				pending[secretName] = true
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		ui.Warn("%d secrets not found, retrying in %s (%d/%d)", len(pending), fctx.opts.retryDelay, attempt, fctx.opts.retryMissing)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(fctx.opts.retryDelay):
+		}
+
+		*missing = (*missing)[:0]
+		g, gctx := errgroup.WithContext(ctx)
+		sem := make(chan struct{}, concurrency)
+		var mu sync.Mutex
+		if err := fetchAllSecrets(gctx, g, sem, fctx, pending, cache, missing, &mu); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func collectSecretsToFetch(cfg *config.Config) map[string]bool {
 	secretsToFetch := make(map[string]bool)
 	for _, mapping := range cfg.Mappings {
 		// Check local environment
-		if localSpec := mapping.GetValueSpec(config.EnvLocal); localSpec != nil && localSpec.IsKeyvaultSecret() {
+		if localSpec := cfg.ValueSpec(mapping, config.EnvLocal); localSpec != nil && localSpec.IsKeyvaultSecret() {
 			secretsToFetch[localSpec.Value] = true
 		}
 		// Check docker environment
-		if dockerSpec := mapping.GetValueSpec(config.EnvDocker); dockerSpec != nil && dockerSpec.IsKeyvaultSecret() {
+		if dockerSpec := cfg.ValueSpec(mapping, config.EnvDocker); dockerSpec != nil && dockerSpec.IsKeyvaultSecret() {
 			secretsToFetch[dockerSpec.Value] = true
 		}
 	}
 	return secretsToFetch
 }
 
-func fetchAllSecrets(gctx context.Context, g *errgroup.Group, sem chan struct{}, fctx *fetchContext, secretsToFetch map[string]bool, localSecrets map[string]string, missing *[]string, mu *sync.Mutex) error {
+func fetchAllSecrets(gctx context.Context, g *errgroup.Group, sem chan struct{}, fctx *fetchContext, secretsToFetch map[string]bool, localSecrets map[string]string, missing *[]missingValue, mu *sync.Mutex) error {
 	for secretName := range secretsToFetch {
 		secretName := secretName
 		sem <- struct{}{}
@@ -132,33 +497,31 @@ func fetchAllSecrets(gctx context.Context, g *errgroup.Group, sem chan struct{},
 	return g.Wait()
 }
 
-func buildResultsFromSecrets(cfg *config.Config, localSecrets map[string]string) ([]secretResult, []string) {
+func buildResultsFromSecrets(cfg *config.Config, localSecrets map[string]string) ([]secretResult, []missingValue, error) {
 	var results []secretResult
-	var missing []string
+	var missing []missingValue
 
 	for envKey, mapping := range cfg.Mappings {
-		// Process local environment
-		if localResult, missingLocal := processEnvironmentMapping(envKey, mapping, config.EnvLocal, localSecrets); localResult != nil {
-			results = append(results, *localResult)
-		} else if missingLocal != "" {
-			missing = append(missing, missingLocal)
-		}
-
-		// Process docker environment
-		if dockerResult, missingDocker := processEnvironmentMapping(envKey, mapping, config.EnvDocker, localSecrets); dockerResult != nil {
-			results = append(results, *dockerResult)
-		} else if missingDocker != "" {
-			missing = append(missing, missingDocker)
+		for _, env := range []config.Environment{config.EnvLocal, config.EnvDocker} {
+			result, missingValue, err := processEnvironmentMapping(cfg, envKey, mapping, env, localSecrets)
+			if err != nil {
+				return nil, nil, err
+			}
+			if result != nil {
+				results = append(results, *result)
+			} else if missingValue != nil {
+				missing = append(missing, *missingValue)
+			}
 		}
 	}
 
-	return results, missing
+	return results, missing, nil
 }
 
-func processEnvironmentMapping(envKey string, mapping config.Mapping, environment config.Environment, localSecrets map[string]string) (*secretResult, string) {
-	spec := mapping.GetValueSpec(environment)
+func processEnvironmentMapping(cfg *config.Config, envKey string, mapping config.Mapping, environment config.Environment, localSecrets map[string]string) (*secretResult, *missingValue, error) {
+	spec := cfg.ValueSpec(mapping, environment)
 	if spec == nil {
-		return nil, ""
+		return nil, nil, nil
 	}
 
 	result := secretResult{
@@ -168,24 +531,33 @@ func processEnvironmentMapping(envKey string, mapping config.Mapping, environmen
 
 	if spec.IsKeyvaultSecret() {
 		if val, exists := localSecrets[spec.Value]; exists {
-			result.value = val
-			return &result, ""
+			value, err := mappedSecretValue(mapping, envKey, environment, val)
+			if err != nil {
+				return nil, nil, err
+			}
+			result.value = value
+			return &result, nil, nil
 		}
-		return nil, fmt.Sprintf("%s (%s) -> %s", envKey, environment, spec.Value)
+		return nil, &missingValue{Key: envKey, Environment: environment, Secret: spec.Value}, nil
 	}
 	result.value = spec.Value
-	return &result, ""
+	return &result, nil, nil
 }
 
-func fetchKeyVaultSecret(ctx context.Context, fctx *fetchContext, secretName string, cache map[string]string, missing *[]string, mu *sync.Mutex) error {
+func fetchKeyVaultSecret(ctx context.Context, fctx *fetchContext, secretName string, cache map[string]string, missing *[]missingValue, mu *sync.Mutex) error {
+	start := time.Now()
 	val, err := fctx.prov.GetSecret(ctx, fctx.vault, secretName)
+	fctx.trace.record(secretName, time.Since(start))
 	if err != nil {
-		if azcli.IsNotFound(err) {
+		if provider.IsNotFound(err) {
 			mu.Lock()
-			*missing = append(*missing, fmt.Sprintf("secret: %s", secretName))
+			*missing = append(*missing, missingValue{Secret: secretName})
 			mu.Unlock()
 			return nil
 		}
+		if provider.IsVaultNotFound(err) || provider.IsAuth(err) {
+			return err // vault-wide problem, not specific to this secret
+		}
 		return fmt.Errorf("failed to get secret %s: %w", secretName, err)
 	}
 	mu.Lock()
@@ -194,15 +566,29 @@ func fetchKeyVaultSecret(ctx context.Context, fctx *fetchContext, secretName str
 	return nil
 }
 
-func reportMissingSecrets(missing []string, vault string) error {
+func reportMissingSecrets(missing []missingValue, vault string, raw bool) error {
+	sortMissing(missing)
+	if raw {
+		if err := outputJSON(missingReport{Vault: vault, Missing: missing}); err != nil {
+			return err
+		}
+		return errors.New("one or more secrets are missing")
+	}
 	ui.Error("missing %d secrets in vault %s:", len(missing), vault)
-	sort.Strings(missing)
 	for _, m := range missing {
 		ui.Error("  - %s", m)
 	}
 	return errors.New("one or more secrets are missing")
 }
 
+func warnSkippedSecrets(missing []missingValue, vault string) {
+	ui.Warn("skipping %d missing values in vault %s:", len(missing), vault)
+	sortMissing(missing)
+	for _, m := range missing {
+		ui.Warn("  - %s", m)
+	}
+}
+
 func buildEnvMaps(results []secretResult, cfg *config.Config) (map[string]string, map[string]string) {
 	envMap := make(map[string]string)    // local environment
 	dockerMap := make(map[string]string) // docker environment
@@ -213,7 +599,20 @@ func buildEnvMaps(results []secretResult, cfg *config.Config) (map[string]string
 	// Handle mappings that don't have environment-specific values
 	fillMissingMappings(cfg, envMap, dockerMap)
 
-	return envMap, dockerMap
+	return applyEnvNames(cfg, envMap), applyEnvNames(cfg, dockerMap)
+}
+
+// applyEnvNames re-keys vars from config keys to the names mappings emit
+func applyEnvNames(cfg *config.Config, vars map[string]string) map[string]string {
+	out := make(map[string]string, len(vars))
+	for key, value := range vars {
+		name := key
+		if mapping, ok := cfg.Mappings[key]; ok {
+			name = mapping.OutputName(key)
+		}
+		out[name] = value
+	}
+	return out
 }
 
 func populateEnvMapsFromResults(results []secretResult, envMap, dockerMap map[string]string) {
@@ -229,26 +628,26 @@ func populateEnvMapsFromResults(results []secretResult, envMap, dockerMap map[st
 
 func fillMissingMappings(cfg *config.Config, envMap, dockerMap map[string]string) {
 	for envKey, mapping := range cfg.Mappings {
-		fillMissingLocalMapping(envKey, mapping, envMap)
-		fillMissingDockerMapping(envKey, mapping, dockerMap, envMap)
+		fillMissingLocalMapping(cfg, envKey, mapping, envMap)
+		fillMissingDockerMapping(cfg, envKey, mapping, dockerMap, envMap)
 	}
 }
 
-func fillMissingLocalMapping(envKey string, mapping config.Mapping, envMap map[string]string) {
+func fillMissingLocalMapping(cfg *config.Config, envKey string, mapping config.Mapping, envMap map[string]string) {
 	if _, exists := envMap[envKey]; !exists {
-		if spec := mapping.GetValueSpec(config.EnvLocal); spec != nil && spec.IsLiteral() {
+		if spec := cfg.ValueSpec(mapping, config.EnvLocal); spec != nil && spec.IsLiteral() {
 			envMap[envKey] = spec.Value
 		}
 	}
 }
 
-func fillMissingDockerMapping(envKey string, mapping config.Mapping, dockerMap, envMap map[string]string) {
+func fillMissingDockerMapping(cfg *config.Config, envKey string, mapping config.Mapping, dockerMap, envMap map[string]string) {
 	if _, exists := dockerMap[envKey]; !exists {
-		if spec := mapping.GetValueSpec(config.EnvDocker); spec != nil {
+		if spec := cfg.ValueSpec(mapping, config.EnvDocker); spec != nil {
 			if spec.IsLiteral() {
 				dockerMap[envKey] = spec.Value
 			}
-		} else {
+		} else if _, custom := cfg.ResolveOrder[config.EnvDocker]; !custom {
 			// If no docker-specific value, fall back to local
 			if localVal, hasLocal := envMap[envKey]; hasLocal {
 				dockerMap[envKey] = localVal
@@ -257,30 +656,195 @@ func fillMissingDockerMapping(envKey string, mapping config.Mapping, dockerMap,
 	}
 }
 
-func writeEnvFiles(envMap, dockerMap map[string]string, fctx *fetchContext) error {
+// writeResult summarises what writeEnvFiles did
+type writeResult struct {
+	envKeys    int
+	dockerKeys int
+	unmapped   int
+	changed    int
+	files      int
+}
+
+// outputFiles returns the files fetch writes with opts
+func outputFiles(opts *fetchOptions) []string {
+	if opts.combine != "" {
+		return []string{opts.combine}
+	}
+	return []string{".env", "docker.env"}
+}
+
+// describeOutputs names the output files for --since-file messages
+func describeOutputs(outputs []string) string {
+	if len(outputs) == 1 {
+		return outputs[0] + " is"
+	}
+	return strings.Join(outputs, " and ") + " are"
+}
+
+// envFilesUpToDate reports whether every output exists and was modified after
+// the config file. A remote config is never considered older.
+func envFilesUpToDate(outputs []string) bool {
+	if remoteConfigURL != "" {
+		return false
+	}
+	cfgInfo, err := os.Stat(configPath)
+	if err != nil {
+		return false
+	}
+	for _, path := range outputs {
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().After(cfgInfo.ModTime()) {
+			return false
+		}
+	}
+	return true
+}
+
+// fetchHeader returns the comment written at the top of generated files. The
+// timestamp is left out with --deterministic-time and taken from
+// SOURCE_DATE_EPOCH when that is set, for reproducible builds.
+func fetchHeader(fctx *fetchContext) (string, error) {
+	header := fmt.Sprintf("# Generated by yeet\n# Source: %s\n# Vault: %s\n", configSource(), fctx.vault)
+	if fctx.opts.deterministicTime {
+		return header, nil
+	}
+
+	generated := time.Now()
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		seconds, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: must be a Unix timestamp", epoch)
+		}
+		generated = time.Unix(seconds, 0).UTC()
+	}
+	return header + fmt.Sprintf("# Generated: %s\n", generated.Format(time.RFC3339)), nil
+}
+
+// writeEnvFiles writes .env and docker.env and reports key and change counts
+func writeEnvFiles(envMap, dockerMap map[string]string, fctx *fetchContext) (*writeResult, error) {
+	if fctx.opts.combine != "" {
+		return writeCombinedEnvFile(envMap, dockerMap, fctx)
+	}
+	if fctx.opts.splitByPrefix {
+		return writePrefixFiles(envMap, dockerMap, fctx)
+	}
+
 	existingEnv, _ := envwriter.ReadKeyValues(".env")
 	existingDocker, _ := envwriter.ReadKeyValues("docker.env")
 
-	finalEnv := envwriter.MergeRetainUnknowns(envMap, existingEnv, fctx.cfg.Mappings)
-	finalDocker := envwriter.MergeRetainUnknowns(dockerMap, existingDocker, fctx.cfg.Mappings)
+	outputMappings := fctx.cfg.OutputMappings()
+	finalEnv := envwriter.MergeRetainUnknowns(envMap, existingEnv, outputMappings)
+	finalDocker := envwriter.MergeRetainUnknowns(dockerMap, existingDocker, outputMappings)
+
+	unmapped := warnUnmappedKeys(existingEnv, existingDocker, outputMappings)
+	fctx.report.addFile(".env", config.EnvLocal, finalEnv, existingEnv, outputMappings)
+	fctx.report.addFile("docker.env", config.EnvDocker, finalDocker, existingDocker, outputMappings)
+
+	header, err := fetchHeader(fctx)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := envwriter.CountChanged(finalEnv, existingEnv) + envwriter.CountChanged(finalDocker, existingDocker)
+
+	envOpts, dockerOpts := fctx.writeOpts, fctx.writeOpts
+	if fctx.opts.annotateUnmanaged {
+		envOpts.Unmanaged = keySet(envwriter.UnmappedKeys(existingEnv, outputMappings))
+		dockerOpts.Unmanaged = keySet(envwriter.UnmappedKeys(existingDocker, outputMappings))
+	}
+
+	if fctx.opts.parallelFiles {
+		if err := writeEnvFilesTogether(finalEnv, finalDocker, header, envOpts, dockerOpts); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := envwriter.WriteEnvFileWithOptions(".env", finalEnv, header, envOpts); err != nil {
+			return nil, err
+		}
+		if err := envwriter.WriteEnvFileWithOptions("docker.env", finalDocker, header, dockerOpts); err != nil {
+			return nil, err
+		}
+	}
 
-	warnUnmappedKeys(existingEnv, existingDocker, fctx.cfg.Mappings)
+	ui.Success("wrote .env and docker.env (%d keys)", len(finalEnv))
+	return &writeResult{
+		envKeys:    len(finalEnv),
+		dockerKeys: len(finalDocker),
+		unmapped:   unmapped,
+		changed:    changed,
+	}, nil
+}
 
-	header := fmt.Sprintf("# Generated by yeet\n# Source: %s\n# Vault: %s\n# Generated: %s\n",
-		configPath, fctx.vault, time.Now().Format(time.RFC3339))
+// writeCombinedEnvFile writes both environments to the --combine file, keeping
+// unmapped keys in the section they were found in
+func writeCombinedEnvFile(envMap, dockerMap map[string]string, fctx *fetchContext) (*writeResult, error) {
+	path := fctx.opts.combine
+	existingEnv, _ := envwriter.ReadSection(path, string(config.EnvLocal))
+	existingDocker, _ := envwriter.ReadSection(path, string(config.EnvDocker))
 
-	if err := envwriter.WriteEnvFile(".env", finalEnv, header); err != nil {
+	outputMappings := fctx.cfg.OutputMappings()
+	finalEnv := envwriter.MergeRetainUnknowns(envMap, existingEnv, outputMappings)
+	finalDocker := envwriter.MergeRetainUnknowns(dockerMap, existingDocker, outputMappings)
+
+	unmapped := len(envwriter.UnmappedKeys(existingEnv, outputMappings)) + len(envwriter.UnmappedKeys(existingDocker, outputMappings))
+	if unmapped > 0 {
+		ui.Warn("retaining %d keys in %s not defined in env.config.json", unmapped, path)
+	}
+
+	header, err := fetchHeader(fctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fctx.report.addFile(path, config.EnvLocal, finalEnv, existingEnv, outputMappings)
+	fctx.report.addFile(path, config.EnvDocker, finalDocker, existingDocker, outputMappings)
+
+	sections := []envwriter.Section{
+		{Name: string(config.EnvLocal), Vars: finalEnv},
+		{Name: string(config.EnvDocker), Vars: finalDocker},
+	}
+	if err := envwriter.WriteCombinedFile(path, sections, header, fctx.writeOpts); err != nil {
+		return nil, err
+	}
+
+	ui.Success("wrote %s (%d local, %d docker keys)", path, len(finalEnv), len(finalDocker))
+	return &writeResult{
+		envKeys:    len(finalEnv),
+		dockerKeys: len(finalDocker),
+		unmapped:   unmapped,
+		changed:    envwriter.CountChanged(finalEnv, existingEnv) + envwriter.CountChanged(finalDocker, existingDocker),
+	}, nil
+}
+
+// writeEnvFilesTogether stages both files before replacing either so a failure
+// never leaves .env and docker.env out of step
+func writeEnvFilesTogether(finalEnv, finalDocker map[string]string, header string, envOpts, dockerOpts envwriter.WriteOptions) error {
+	stagedEnv, err := envwriter.StageEnvFile(".env", finalEnv, header, envOpts)
+	if err != nil {
 		return err
 	}
-	if err := envwriter.WriteEnvFile("docker.env", finalDocker, header); err != nil {
+	defer stagedEnv.Discard()
+
+	stagedDocker, err := envwriter.StageEnvFile("docker.env", finalDocker, header, dockerOpts)
+	if err != nil {
 		return err
 	}
+	defer stagedDocker.Discard()
 
-	ui.Success("wrote .env and docker.env (%d keys)", len(finalEnv))
-	return nil
+	return envwriter.CommitAll(stagedEnv, stagedDocker)
+}
+
+// keySet converts keys to a lookup set
+func keySet(keys []string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		set[k] = true
+	}
+	return set
 }
 
-func warnUnmappedKeys(existingEnv, existingDocker map[string]string, mappings map[string]config.Mapping) {
+// warnUnmappedKeys warns about retained keys and returns how many there are
+func warnUnmappedKeys(existingEnv, existingDocker map[string]string, mappings map[string]config.Mapping) int {
 	unmappedEnv := envwriter.UnmappedKeys(existingEnv, mappings)
 	unmappedDocker := envwriter.UnmappedKeys(existingDocker, mappings)
 
@@ -293,4 +857,5 @@ func warnUnmappedKeys(existingEnv, existingDocker map[string]string, mappings ma
 			ui.Warn("  - docker.env: %s", k)
 		}
 	}
+	return len(unmappedEnv) + len(unmappedDocker)
 }
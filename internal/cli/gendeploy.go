@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+)
+
+type genDeploymentOptions struct {
+	env           string
+	secretRefName string
+}
+
+func newGenDeploymentEnvCmd() *cobra.Command {
+	opts := &genDeploymentOptions{}
+	cmd := &cobra.Command{
+		Use:   "gen-deployment-env",
+		Short: "Generate Kubernetes container env entries from the configuration",
+		Long: `Generate a Kubernetes container "env:" block from the configuration.
+
+Key Vault backed values are emitted as valueFrom.secretKeyRef entries keyed by
+the secret name; literal values are emitted inline. This is the inverse of
+"yeet compare" and can be pasted straight into a deployment manifest.`,
+		Example: `  yeet gen-deployment-env
+  yeet gen-deployment-env --env local > env.yaml
+  yeet gen-deployment-env --secret-ref-name myapp-secrets`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGenDeploymentEnv(opts, cmd.Flags().Changed("env"))
+		},
+	}
+	cmd.Flags().StringVarP(&opts.env, "env", "e", "docker", "Environment to generate entries for (local|docker)")
+	_ = cmd.RegisterFlagCompletionFunc("env", completeEnvironments)
+	cmd.Flags().StringVar(&opts.secretRefName, "secret-ref-name", "", "Kubernetes Secret name used in secretKeyRef entries (default: the Key Vault name)")
+	return cmd
+}
+
+func runGenDeploymentEnv(opts *genDeploymentOptions, envFlagSet bool) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	env, err := parseEnvironment(defaultEnvironment(cfg, opts.env, envFlagSet))
+	if err != nil {
+		return err
+	}
+
+	secretRefName := opts.secretRefName
+	if secretRefName == "" {
+		secretRefName = cfg.KeyVaultName
+		if vaultOverride != "" {
+			secretRefName = vaultOverride
+		}
+	}
+
+	entries := buildDeploymentEnv(cfg, env, secretRefName)
+
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	defer enc.Close()
+	return enc.Encode(struct {
+		Env []EnvVar `yaml:"env"`
+	}{Env: entries})
+}
+
+// buildDeploymentEnv converts config mappings into container env entries,
+// referencing secretName for Key Vault backed values
+func buildDeploymentEnv(cfg *config.Config, env config.Environment, secretName string) []EnvVar {
+	keys := make([]string, 0, len(cfg.Mappings))
+	for key := range cfg.Mappings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]EnvVar, 0, len(keys))
+	for _, key := range keys {
+		mapping := cfg.Mappings[key]
+		spec := cfg.ValueSpec(mapping, env)
+		if spec == nil {
+			continue
+		}
+
+		entry := EnvVar{Name: mapping.OutputName(key)}
+		if spec.IsKeyvaultSecret() {
+			entry.ValueFrom = &EnvVarValueSource{
+				SecretKeyRef: &SecretKeyRef{Name: secretName, Key: spec.Value},
+			}
+		} else {
+			entry.Value = spec.Value
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+)
+
+type getOptions struct {
+	secret      string
+	showSecrets bool
+}
+
+func newGetCmd() *cobra.Command {
+	opts := &getOptions{}
+	cmd := &cobra.Command{
+		Use:   "get --secret NAME",
+		Short: "Read a single secret from Key Vault by name, without the config mappings",
+		Long: `Read one secret directly by its Key Vault name. Mappings are not used; the
+vault comes from --vault, or from the config's keyVaultName if --vault is not
+given. The value is masked unless --show-secrets is set.`,
+		Example: `  yeet get --secret db-password --vault my-vault
+  yeet get --secret api-key --show-secrets`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGet(cmd.Context(), opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.secret, "secret", "", "Key Vault secret name to read")
+	cmd.Flags().BoolVar(&opts.showSecrets, "show-secrets", false, "Print the value instead of masking it")
+	_ = cmd.MarkFlagRequired("secret")
+	return cmd
+}
+
+func runGet(ctx context.Context, opts *getOptions) error {
+	var cfg *config.Config
+	vault := vaultOverride
+	if vault == "" {
+		var err error
+		if cfg, err = loadConfig(); err != nil {
+			return fmt.Errorf("--vault is required without a usable config: %w", err)
+		}
+		vault = cfg.KeyVaultName
+	}
+
+	prov := newProvider(cfg)
+	if err := ensureLoggedIn(ctx, prov); err != nil {
+		return err
+	}
+
+	value, err := prov.GetSecret(ctx, vault, opts.secret)
+	if err != nil {
+		return err
+	}
+
+	if !opts.showSecrets {
+		value = maskedValue
+	}
+	fmt.Println(value)
+	return nil
+}
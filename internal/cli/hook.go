@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newHookCmd() *cobra.Command {
+	var env string
+	cmd := &cobra.Command{
+		Use:   "hook direnv|bash|zsh",
+		Short: "Print a snippet that loads secrets into your shell automatically",
+		Long: `Print shell integration for 'yeet export'.
+
+  direnv     an .envrc snippet; direnv loads the secrets when you cd into
+             the project and reloads them when the config changes
+  bash, zsh  a yeet_load function to add to your shell profile; run it in
+             a project to export its secrets into the current shell
+
+Flags such as --env and --config are passed through to 'yeet export'.`,
+		Example: `  yeet hook direnv >> .envrc && direnv allow
+  yeet hook direnv --env docker >> .envrc
+  yeet hook bash >> ~/.bashrc`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"direnv", "bash", "zsh"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			exportCmd := "yeet export" + hookFlags(cmd, env)
+			switch args[0] {
+			case "direnv":
+				fmt.Println("# Load secrets with yeet")
+				if remoteConfigURL == "" {
+					fmt.Printf("watch_file %s\n", shellQuote(configPath))
+				}
+				fmt.Printf("eval \"$(%s)\"\n", exportCmd)
+			case "bash", "zsh":
+				fmt.Printf("# Export the current project's secrets from yeet\nyeet_load() {\n  local exports\n  exports=\"$(command %s \"$@\")\" || return\n  eval \"$exports\"\n}\n", exportCmd)
+			default:
+				return fmt.Errorf("unsupported shell %q: must be direnv, bash or zsh", args[0])
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&env, "env", "e", "local", "Environment the snippet exports (local|docker)")
+	_ = cmd.RegisterFlagCompletionFunc("env", completeEnvironments)
+	return cmd
+}
+
+// hookFlags renders the flags given to 'yeet hook' that 'yeet export' also needs
+func hookFlags(cmd *cobra.Command, env string) string {
+	var b strings.Builder
+	if cmd.Flags().Changed("env") {
+		fmt.Fprintf(&b, " --env %s", shellQuote(env))
+	}
+	if cmd.Flags().Changed("config") {
+		fmt.Fprintf(&b, " --config %s", shellQuote(configSource()))
+	}
+	if cmd.Flags().Changed("vault") {
+		fmt.Fprintf(&b, " --vault %s", shellQuote(vaultOverride))
+	}
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+	"github.com/JayDubyaEey/yeet/internal/provider"
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+// expandIncludes lists the vault and adds a global keyvault mapping for every
+// secret matched by the config's includes. Explicit mappings always win; when
+// two included secrets give the same env key, the first by name wins. It
+// returns the env keys that were added, sorted.
+func expandIncludes(ctx context.Context, prov provider.Provider, vault string, cfg *config.Config) ([]string, error) {
+	if len(cfg.Includes) == 0 {
+		return nil, nil
+	}
+
+	lister, ok := prov.(provider.Lister)
+	if !ok {
+		return nil, fmt.Errorf("config uses includes but the provider cannot list secrets")
+	}
+
+	names, err := lister.ListSecrets(ctx, vault)
+	if err != nil {
+		return nil, err
+	}
+
+	nameRegex, err := cfg.NameRegexp()
+	if err != nil {
+		return nil, err
+	}
+
+	// Explicit mappings win, whether matched by key or by emitted envName
+	emitted := cfg.OutputMappings()
+
+	// Visit secrets in name order so collisions resolve the same way every run
+	names = append([]string(nil), names...)
+	sort.Strings(names)
+
+	includedFrom := make(map[string]string) // env key -> secret it was included from
+	var added []string
+	for _, secretName := range names {
+		for _, inc := range cfg.Includes {
+			if !inc.Matches(secretName) {
+				continue
+			}
+			envKey := inc.EnvKey(secretName)
+			if first, exists := includedFrom[envKey]; exists {
+				ui.Warn("skipping included secret %s: %s is already included from %s", secretName, envKey, first)
+				break
+			}
+			if _, exists := cfg.Mappings[envKey]; exists {
+				break
+			}
+			if _, exists := emitted[envKey]; exists {
+				break
+			}
+			if !nameRegex.MatchString(envKey) {
+				ui.Warn("skipping included secret %s: %s is not a valid env var name", secretName, envKey)
+				break
+			}
+			cfg.Mappings[envKey] = config.Mapping{Type: config.ValueTypeKeyvault, Value: secretName}
+			includedFrom[envKey] = secretName
+			added = append(added, envKey)
+			break
+		}
+	}
+
+	sort.Strings(added)
+	ui.Info("discovered %d secrets from includes", len(added))
+	return added, nil
+}
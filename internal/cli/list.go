@@ -7,7 +7,7 @@ import (
 	"sort"
 
 	"github.com/JayDubyaEey/yeet/internal/config"
-	"github.com/JayDubyaEey/yeet/internal/provider/azcli"
+	"github.com/JayDubyaEey/yeet/internal/provider"
 	"github.com/JayDubyaEey/yeet/internal/ui"
 	"github.com/spf13/cobra"
 )
@@ -45,9 +45,13 @@ func runList(ctx context.Context, opts *listOptions) error {
 		return err
 	}
 
-	prov := azcli.NewDefault()
-	if err := prov.EnsureLoggedIn(context.Background()); err != nil {
-		return fmt.Errorf("not logged in to Azure CLI: %w (run: yeet login)", err)
+	prov := newProvider(cfg)
+	if err := ensureLoggedIn(ctx, prov); err != nil {
+		return err
+	}
+
+	if _, err := expandIncludes(ctx, prov, vault, cfg); err != nil {
+		return err
 	}
 
 	rows, err := fetchSecretStatuses(ctx, cfg, vault, prov)
@@ -65,7 +69,7 @@ func runList(ctx context.Context, opts *listOptions) error {
 }
 
 func loadConfigAndVault() (*config.Config, string, error) {
-	cfg, err := config.Load(configPath)
+	cfg, err := loadConfig()
 	if err != nil {
 		return nil, "", err
 	}
@@ -78,17 +82,17 @@ func loadConfigAndVault() (*config.Config, string, error) {
 	return cfg, vault, nil
 }
 
-func fetchSecretStatuses(ctx context.Context, cfg *config.Config, vault string, prov *azcli.Provider) ([]secretRow, error) {
+func fetchSecretStatuses(ctx context.Context, cfg *config.Config, vault string, prov provider.Provider) ([]secretRow, error) {
 	secretsToCheck := make(map[string][]string) // secret name -> list of env vars that use it
 
 	// Collect all unique secrets from all environments
 	for envKey, mapping := range cfg.Mappings {
 		// Check local environment
-		if localSpec := mapping.GetValueSpec(config.EnvLocal); localSpec != nil && localSpec.IsKeyvaultSecret() {
+		if localSpec := cfg.ValueSpec(mapping, config.EnvLocal); localSpec != nil && localSpec.IsKeyvaultSecret() {
 			secretsToCheck[localSpec.Value] = append(secretsToCheck[localSpec.Value], envKey+"(local)")
 		}
 		// Check docker environment
-		if dockerSpec := mapping.GetValueSpec(config.EnvDocker); dockerSpec != nil && dockerSpec.IsKeyvaultSecret() {
+		if dockerSpec := cfg.ValueSpec(mapping, config.EnvDocker); dockerSpec != nil && dockerSpec.IsKeyvaultSecret() {
 			secretsToCheck[dockerSpec.Value] = append(secretsToCheck[dockerSpec.Value], envKey+"(docker)")
 		}
 	}
@@ -109,8 +113,8 @@ func fetchSecretStatuses(ctx context.Context, cfg *config.Config, vault string,
 	return rows, nil
 }
 
-func outputJSON(rows []secretRow) error {
-	b, err := json.MarshalIndent(rows, "", "  ")
+func outputJSON(v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
 		return err
 	}
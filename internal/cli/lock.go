@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+// fetchLockFile serializes fetches writing to the same directory
+const fetchLockFile = ".yeet.lock"
+
+// acquireFetchLock waits up to timeout for the fetch lock at path and returns
+// a function that releases it
+func acquireFetchLock(ctx context.Context, path string, timeout time.Duration) (func(), error) {
+	deadline := time.Now().Add(timeout)
+	waiting := false
+	for {
+		release, ok, err := tryLock(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if ok {
+			return release, nil
+		}
+
+		if !waiting {
+			ui.Info("waiting for another yeet fetch to finish (%s)", path)
+			waiting = true
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out after %s waiting for %s; another fetch is running (use --no-lock to skip locking)", timeout, path)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package cli
+
+import "os"
+
+// tryLock creates path exclusively; it is removed again on release. A fetch
+// that is killed leaves the file behind, so --no-lock or deleting it recovers.
+func tryLock(path string) (func(), bool, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0600)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return func() {
+		f.Close()
+		os.Remove(path)
+	}, true, nil
+}
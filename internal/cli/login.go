@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/JayDubyaEey/yeet/internal/provider/azcli"
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+type loginOptions struct {
+	tenant        string
+	subscription  string
+	useDeviceCode bool
+}
+
+func newLoginCmd() *cobra.Command {
+	opts := &loginOptions{}
+	cmd := &cobra.Command{
+		Use:   "login",
+		Short: "Log in to Azure CLI",
+		Long: `Log in to Azure CLI with 'az login'. On machines that cannot open a browser
+(SSH sessions, remote dev boxes) use --use-device-code and enter the printed
+code on another device.`,
+		Example: `  yeet login
+  yeet login --tenant contoso.onmicrosoft.com --subscription my-subscription
+  yeet login --use-device-code`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLogin(cmd.Context(), opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.tenant, "tenant", "", "Tenant to log in to")
+	cmd.Flags().StringVar(&opts.subscription, "subscription", "", "Subscription to select after logging in")
+	cmd.Flags().BoolVar(&opts.useDeviceCode, "use-device-code", false, "Log in with a device code instead of opening a browser")
+	return cmd
+}
+
+func runLogin(ctx context.Context, opts *loginOptions) error {
+	prov := azcli.NewDefault()
+	if err := prov.Login(ctx, azcli.LoginOptions{
+		Tenant:        opts.tenant,
+		Subscription:  opts.subscription,
+		UseDeviceCode: opts.useDeviceCode,
+	}); err != nil {
+		return err
+	}
+	ui.Success("logged in to Azure CLI")
+	return nil
+}
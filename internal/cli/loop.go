@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+	"github.com/JayDubyaEey/yeet/internal/provider"
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+// runFetchLoop re-runs fetch every interval until SIGINT/SIGTERM. Failures are
+// logged and retried on the next cycle rather than ending the process.
+func runFetchLoop(ctx context.Context, opts *fetchOptions, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid --interval %s: must be positive", interval)
+	}
+
+	// Report changes per cycle through the --diff-exit signal
+	opts.diffExit = true
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ui.Success("refreshing every %s (Ctrl+C or SIGTERM to stop)", interval)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for cycle := 1; ; cycle++ {
+		select {
+		case <-ctx.Done():
+			ui.Success("stopped after %d refreshes", cycle-1)
+			return nil
+		case <-timer.C:
+		}
+
+		runRefreshCycle(ctx, opts, cycle)
+		timer.Reset(interval)
+	}
+}
+
+func runRefreshCycle(ctx context.Context, opts *fetchOptions, cycle int) {
+	// The config supplies the vault DNS suffix; runFetch reports it if unusable
+	cfg, _ := config.Load(configPath)
+	if warmer, ok := newProvider(cfg).(provider.TokenWarmer); ok {
+		if err := warmer.WarmToken(ctx); err != nil {
+			ui.Warn("refresh %d: could not warm token: %v", cycle, err)
+		}
+	}
+
+	err := runFetch(ctx, opts)
+	var exitErr *exitCodeError
+	switch {
+	case err == nil:
+		ui.Info("refresh %d: no changes", cycle)
+	case errors.As(err, &exitErr) && exitErr.code == 2:
+		ui.Success("refresh %d: values changed", cycle)
+	case ctx.Err() != nil:
+		// Shutting down; the loop reports that
+	default:
+		ui.Error("refresh %d failed: %v (will retry)", cycle, err)
+	}
+}
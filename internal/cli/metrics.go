@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fetchMetrics holds the values reported after a fetch/refresh run
+type fetchMetrics struct {
+	duration       time.Duration
+	secretsTotal   int
+	secretsChanged int
+	lastSuccess    time.Time
+}
+
+// writeMetricsFile writes metrics in the node exporter textfile collector format.
+// The file is replaced atomically so the collector never reads a partial file.
+func writeMetricsFile(path string, m fetchMetrics) error {
+	var b strings.Builder
+	writeMetric(&b, "yeet_fetch_duration_seconds", "gauge", "Duration of the last fetch in seconds.", fmt.Sprintf("%g", m.duration.Seconds()))
+	writeMetric(&b, "yeet_secrets_total", "gauge", "Number of Key Vault secrets referenced by the config.", fmt.Sprintf("%d", m.secretsTotal))
+	writeMetric(&b, "yeet_secrets_changed_total", "gauge", "Number of env values that changed in the last fetch.", fmt.Sprintf("%d", m.secretsChanged))
+	writeMetric(&b, "yeet_last_success_timestamp", "gauge", "Unix time of the last successful fetch.", fmt.Sprintf("%d", m.lastSuccess.Unix()))
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".metrics-tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // Clean up on any error
+
+	if _, err := tmp.WriteString(b.String()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	tmp.Close()
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write metrics file %s: %w", path, err)
+	}
+	return nil
+}
+
+func writeMetric(b *strings.Builder, name, kind, help, value string) {
+	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, kind)
+	fmt.Fprintf(b, "%s %s\n", name, value)
+}
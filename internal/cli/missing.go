@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+)
+
+// missingValue is a value that could not be resolved. Key and Environment are
+// empty when the entry only records that the vault lacks Secret. Disabled
+// marks a secret that exists but cannot be read.
+type missingValue struct {
+	Key         string             `json:"key,omitempty"`
+	Environment config.Environment `json:"environment,omitempty"`
+	Secret      string             `json:"secret"`
+	Disabled    bool               `json:"disabled,omitempty"`
+}
+
+func (m missingValue) String() string {
+	if m.Key == "" {
+		return "secret: " + m.Secret
+	}
+	return fmt.Sprintf("%s (%s) -> %s", m.Key, m.Environment, m.Secret)
+}
+
+// splitDisabled separates entries for disabled secrets from missing ones
+func splitDisabled(entries []missingValue) (missing, disabled []missingValue) {
+	for _, m := range entries {
+		if m.Disabled {
+			disabled = append(disabled, m)
+		} else {
+			missing = append(missing, m)
+		}
+	}
+	return missing, disabled
+}
+
+// missingReport is the --raw form of a missing-values report
+type missingReport struct {
+	Vault       string             `json:"vault,omitempty"`
+	Environment config.Environment `json:"environment,omitempty"`
+	Missing     []missingValue     `json:"missing"`
+}
+
+// sortMissing orders entries by their text form, as the text reports print them
+func sortMissing(missing []missingValue) {
+	sort.Slice(missing, func(i, j int) bool { return missing[i].String() < missing[j].String() })
+}
+
+// parseEnvVarUse splits a "KEY(env)" entry as built by collectSecretsToValidate
+func parseEnvVarUse(envVar string) (string, config.Environment) {
+	key, env, ok := strings.Cut(envVar, "(")
+	if !ok {
+		return envVar, ""
+	}
+	return key, config.Environment(strings.TrimSuffix(env, ")"))
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+	"github.com/JayDubyaEey/yeet/internal/provider"
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+// findUnreferencedSecrets lists the vault and returns, in vault order, the
+// secrets that no mapping references in any environment
+func findUnreferencedSecrets(ctx context.Context, prov provider.Provider, vault string, cfg *config.Config) ([]string, error) {
+	lister, ok := prov.(provider.Lister)
+	if !ok {
+		return nil, fmt.Errorf("provider cannot list secrets")
+	}
+
+	names, err := lister.ListSecrets(ctx, vault)
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := collectSecretsToFetch(cfg)
+	unreferenced := make([]string, 0)
+	for _, name := range names {
+		if !referenced[name] {
+			unreferenced = append(unreferenced, name)
+		}
+	}
+	return unreferenced, nil
+}
+
+// reportUnreferencedSecrets prints the vault health check result
+func reportUnreferencedSecrets(unreferenced []string, vault string, raw bool) error {
+	if raw {
+		return outputJSON(unreferenced)
+	}
+
+	if len(unreferenced) == 0 {
+		ui.Success("every secret in %s is referenced by the config", vault)
+		return nil
+	}
+
+	ui.Warn("%d secrets in vault %s are not referenced by any mapping:", len(unreferenced), vault)
+	for _, name := range unreferenced {
+		ui.Warn("  - %s", name)
+	}
+	return nil
+}
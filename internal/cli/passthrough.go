@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+// envPassthrough holds the --env-passthrough glob patterns
+var envPassthrough []string
+
+// checkPassthroughPatterns validates --env-passthrough before any secrets are fetched
+func checkPassthroughPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid --env-passthrough pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// parentEnv returns the environment the command inherits: all of yeet's own,
+// or only the variables matching --env-passthrough when it is given
+func parentEnv() []string {
+	environ := os.Environ()
+	if len(envPassthrough) == 0 {
+		return environ
+	}
+
+	// Non-nil, since exec.Cmd treats a nil Env as inheriting everything
+	kept := []string{}
+	for _, entry := range environ {
+		key, _, _ := strings.Cut(entry, "=")
+		for _, pattern := range envPassthrough {
+			if ok, _ := path.Match(pattern, key); ok {
+				kept = append(kept, entry)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+var (
+	// envAppend holds the --env-append keys and envSeparator --env-separator
+	envAppend    []string
+	envSeparator string
+)
+
+// appendInherited makes each --env-append key extend the value the command
+// would inherit, PATH-style, instead of replacing it
+func appendInherited(envVars map[string]string) {
+	if len(envAppend) == 0 {
+		return
+	}
+	inherited := make(map[string]string)
+	for _, entry := range parentEnv() {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			inherited[key] = value
+		}
+	}
+	for _, key := range envAppend {
+		value, ok := envVars[key]
+		if !ok {
+			continue
+		}
+		if parent := inherited[key]; parent != "" {
+			envVars[key] = parent + envSeparator + value
+			ui.Info("appended %s to the inherited value", key)
+		}
+	}
+}
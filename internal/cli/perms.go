@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/JayDubyaEey/yeet/internal/envwriter"
+)
+
+// parseWriteOptions turns --mode and --owner flag values into envwriter options
+func parseWriteOptions(mode, owner string) (envwriter.WriteOptions, error) {
+	opts := envwriter.DefaultWriteOptions()
+
+	if mode != "" {
+		m, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil || m > 0777 {
+			return opts, fmt.Errorf("invalid --mode %q: must be an octal permission such as 0600", mode)
+		}
+		opts.Mode = os.FileMode(m)
+	}
+
+	if owner != "" {
+		uid, gid, err := lookupOwner(owner)
+		if err != nil {
+			return opts, err
+		}
+		opts.UID, opts.GID = uid, gid
+	}
+
+	return opts, nil
+}
+
+// lookupOwner resolves "user[:group]" (names or numeric ids) to uid and gid;
+// gid is -1 when no group is given
+func lookupOwner(owner string) (int, int, error) {
+	userPart, groupPart, hasGroup := strings.Cut(owner, ":")
+
+	uid, err := strconv.Atoi(userPart)
+	if err != nil {
+		u, lookupErr := user.Lookup(userPart)
+		if lookupErr != nil {
+			return 0, 0, fmt.Errorf("invalid --owner %q: %w", owner, lookupErr)
+		}
+		if uid, err = strconv.Atoi(u.Uid); err != nil {
+			return 0, 0, fmt.Errorf("owner %q has non-numeric uid %s", userPart, u.Uid)
+		}
+	}
+
+	gid := -1
+	if hasGroup && groupPart != "" {
+		if gid, err = strconv.Atoi(groupPart); err != nil {
+			g, lookupErr := user.LookupGroup(groupPart)
+			if lookupErr != nil {
+				return 0, 0, fmt.Errorf("invalid --owner %q: %w", owner, lookupErr)
+			}
+			if gid, err = strconv.Atoi(g.Gid); err != nil {
+				return 0, 0, fmt.Errorf("group %q has non-numeric gid %s", groupPart, g.Gid)
+			}
+		}
+	}
+
+	return uid, gid, nil
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+// findPlaceholderLiterals returns a "KEY (env): value" entry for every literal
+// value that looks like an unreplaced template value. Literals are not
+// secrets, so showing them is safe.
+func findPlaceholderLiterals(cfg *config.Config) []string {
+	var found []string
+	for key, mapping := range cfg.Mappings {
+		for _, env := range []config.Environment{config.EnvLocal, config.EnvDocker} {
+			spec := cfg.ValueSpec(mapping, env)
+			if spec.IsLiteral() && config.LooksLikePlaceholder(spec.Value) {
+				found = append(found, fmt.Sprintf("%s (%s): %q", key, env, spec.Value))
+			}
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+// warnPlaceholderLiterals warns about literals that should probably have been
+// replaced with a Key Vault reference
+func warnPlaceholderLiterals(cfg *config.Config) {
+	found := findPlaceholderLiterals(cfg)
+	if len(found) == 0 {
+		return
+	}
+	ui.Warn("%d literal values look like placeholders (map them to a Key Vault secret instead):", len(found))
+	for _, entry := range found {
+		ui.Warn("  - %s", entry)
+	}
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+	"github.com/JayDubyaEey/yeet/internal/envwriter"
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+// groupByPrefix splits vars by the segment before their first underscore,
+// keyed by that segment lowercased; keys without one are grouped under "".
+// With strip set the prefix is removed from the grouped keys.
+func groupByPrefix(vars map[string]string, strip bool) map[string]map[string]string {
+	groups := make(map[string]map[string]string)
+	for key, value := range vars {
+		group, name := "", key
+		if prefix, rest, ok := strings.Cut(key, "_"); ok && prefix != "" && rest != "" {
+			group = strings.ToLower(prefix)
+			if strip {
+				name = rest
+			}
+		}
+		if groups[group] == nil {
+			groups[group] = make(map[string]string)
+		}
+		groups[group][name] = value
+	}
+	return groups
+}
+
+// writePrefixFiles writes each prefix group of .env and docker.env to its own
+// file, such as .env.api and docker.env.api. Keys without a prefix stay in
+// .env and docker.env, which keep retaining unmapped keys as usual; the
+// group files are written with exactly their group's keys.
+func writePrefixFiles(envMap, dockerMap map[string]string, fctx *fetchContext) (*writeResult, error) {
+	header, err := fetchHeader(fctx)
+	if err != nil {
+		return nil, err
+	}
+	outputMappings := fctx.cfg.OutputMappings()
+	existingEnv, _ := envwriter.ReadKeyValues(".env")
+	existingDocker, _ := envwriter.ReadKeyValues("docker.env")
+	result := &writeResult{unmapped: warnUnmappedKeys(existingEnv, existingDocker, outputMappings)}
+
+	outputs := []struct {
+		base     string
+		env      config.Environment
+		vars     map[string]string
+		existing map[string]string
+		keys     *int
+	}{
+		{".env", config.EnvLocal, envMap, existingEnv, &result.envKeys},
+		{"docker.env", config.EnvDocker, dockerMap, existingDocker, &result.dockerKeys},
+	}
+	for _, out := range outputs {
+		groups := groupByPrefix(out.vars, fctx.opts.stripPrefix)
+		names := []string{""}
+		for group := range groups {
+			if group != "" {
+				names = append(names, group)
+			}
+		}
+		sort.Strings(names)
+
+		for _, group := range names {
+			path, vars, existing, opts := out.base, groups[group], out.existing, fctx.writeOpts
+			if group == "" {
+				vars = envwriter.MergeRetainUnknowns(vars, existing, outputMappings)
+				if fctx.opts.annotateUnmanaged {
+					opts.Unmanaged = keySet(envwriter.UnmappedKeys(existing, outputMappings))
+				}
+			} else {
+				path = out.base + "." + group
+				existing, _ = envwriter.ReadKeyValues(path)
+			}
+
+			if err := envwriter.WriteEnvFileWithOptions(path, vars, header, opts); err != nil {
+				return nil, err
+			}
+			fctx.report.addFile(path, out.env, vars, existing, outputMappings)
+			result.changed += envwriter.CountChanged(vars, existing)
+			result.files++
+			*out.keys += len(vars)
+
+			keys := make([]string, 0, len(vars))
+			for key := range vars {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			ui.Success("wrote %s (%d keys) %s", path, len(keys), strings.Join(keys, ", "))
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+	"github.com/JayDubyaEey/yeet/internal/provider"
+	"github.com/JayDubyaEey/yeet/internal/provider/awssm"
+	"github.com/JayDubyaEey/yeet/internal/provider/azcli"
+	"github.com/JayDubyaEey/yeet/internal/provider/execprov"
+)
+
+const (
+	providerAzCLI = "azcli"
+	providerAWS   = "aws"
+	providerExec  = "exec"
+)
+
+// checkProviderFlags validates --provider and --provider-cmd together
+func checkProviderFlags() error {
+	switch providerName {
+	case "", providerAzCLI, providerAWS:
+		if providerCmd != "" {
+			return fmt.Errorf("--provider-cmd requires --provider %s", providerExec)
+		}
+	case providerExec:
+		if providerCmd == "" {
+			return fmt.Errorf("--provider %s requires --provider-cmd", providerExec)
+		}
+	default:
+		return fmt.Errorf("invalid --provider %q: must be %q, %q or %q", providerName, providerAzCLI, providerAWS, providerExec)
+	}
+	return nil
+}
+
+// activeProvider returns the backend --provider selects, falling back to the
+// config's provider and then the Azure CLI
+func activeProvider(cfg *config.Config) string {
+	if providerName != "" {
+		return providerName
+	}
+	if cfg != nil && cfg.Provider == config.ProviderAWS {
+		return providerAWS
+	}
+	return providerAzCLI
+}
+
+// newProvider returns the secret backend used by commands. cfg may be nil
+// when no config was loaded; it supplies the backend, vault DNS suffix and
+// AWS region otherwise.
+func newProvider(cfg *config.Config) provider.Provider {
+	switch activeProvider(cfg) {
+	case providerExec:
+		return execprov.New(providerCmd)
+	case providerAWS:
+		region := ""
+		if cfg != nil {
+			region = cfg.AWSRegion
+		}
+		return awssm.New(region)
+	}
+	suffix := vaultDNS
+	if suffix == "" && cfg != nil {
+		suffix = cfg.VaultDNSSuffix
+	}
+	if suffix != "" && suffix != config.DefaultVaultDNSSuffix {
+		return azcli.NewWithDNSSuffix(suffix)
+	}
+	return azcli.NewDefault()
+}
+
+// ensureLoggedIn runs the provider's login check unless --no-login-check is set
+func ensureLoggedIn(ctx context.Context, prov provider.Provider) error {
+	if noLoginCheck {
+		return nil
+	}
+	if err := prov.EnsureLoggedIn(ctx); err != nil {
+		return notLoggedIn(prov, err)
+	}
+	return nil
+}
+
+// notLoggedIn wraps a failed login check, naming the fix when prov knows it
+func notLoggedIn(prov provider.Provider, err error) error {
+	if h, ok := prov.(provider.LoginHinter); ok {
+		return fmt.Errorf("not logged in: %w (run: %s)", err, h.LoginHint())
+	}
+	return fmt.Errorf("not logged in: %w", err)
+}
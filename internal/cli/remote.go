@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+)
+
+const (
+	// configAuthEnv optionally holds the Authorization header for a remote --config
+	configAuthEnv = "YEET_CONFIG_AUTHORIZATION"
+
+	remoteConfigTimeout = 15 * time.Second
+)
+
+// remoteConfigURL is the original --config value when it was a URL; configPath
+// then points at a private temp copy for the rest of the run
+var remoteConfigURL string
+
+// fetchRemoteConfig downloads a --config URL to a temp file and points
+// configPath at it. The returned cleanup removes the file.
+func fetchRemoteConfig(ctx context.Context) (func(), error) {
+	if !config.IsRemote(configPath) {
+		return func() {}, nil
+	}
+
+	data, err := config.FetchRemote(ctx, configPath, os.Getenv(configAuthEnv), remoteConfigTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	// CreateTemp already uses 0600, so the copy is private to this user
+	tmp, err := os.CreateTemp("", "yeet-config-*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to cache remote config: %w", err)
+	}
+	cleanup := func() { os.Remove(tmp.Name()) }
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		cleanup()
+		return nil, fmt.Errorf("failed to cache remote config: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("failed to cache remote config: %w", err)
+	}
+
+	remoteConfigURL = configPath
+	configPath = tmp.Name()
+	return cleanup, nil
+}
+
+// configSource names the config for messages and file headers
+func configSource() string {
+	if remoteConfigURL != "" {
+		return remoteConfigURL
+	}
+	return configPath
+}
+
+// rejectRemoteConfig stops commands that would rewrite the config file
+func rejectRemoteConfig() error {
+	if remoteConfigURL != "" {
+		return fmt.Errorf("cannot modify remote config %s; download it and pass a local --config", remoteConfigURL)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+	"github.com/JayDubyaEey/yeet/internal/envwriter"
+)
+
+// fetchReport is the --report summary of a fetch run. It names keys but
+// never includes their values.
+type fetchReport struct {
+	path string
+
+	Success      bool                 `json:"success"`
+	Error        string               `json:"error,omitempty"`
+	Timestamp    time.Time            `json:"timestamp"`
+	DurationMS   int64                `json:"durationMs"`
+	Provider     string               `json:"provider"`
+	Vault        string               `json:"vault,omitempty"`
+	Environments []config.Environment `json:"environments"`
+	Files        []fileReport         `json:"files"`
+	Missing      []missingValue       `json:"missing"`
+}
+
+// fileReport describes the keys written to one env file (or one section of a
+// --combine file)
+type fileReport struct {
+	Path        string             `json:"path"`
+	Environment config.Environment `json:"environment"`
+	Keys        []string           `json:"keys"`
+	Added       []string           `json:"added"`
+	Changed     []string           `json:"changed"`
+	Removed     []string           `json:"removed"`
+	Unmapped    []string           `json:"unmapped"`
+}
+
+// newFetchReport returns a report that will be written to path, or nil when
+// --report was not given; every method is a no-op on a nil report
+func newFetchReport(path string) *fetchReport {
+	if path == "" {
+		return nil
+	}
+	return &fetchReport{
+		path:         path,
+		Provider:     activeProvider(nil),
+		Environments: []config.Environment{config.EnvLocal, config.EnvDocker},
+		Files:        []fileReport{},
+		Missing:      []missingValue{},
+	}
+}
+
+func (r *fetchReport) setVault(vault string) {
+	if r != nil {
+		r.Vault = vault
+	}
+}
+
+func (r *fetchReport) setProvider(name string) {
+	if r != nil {
+		r.Provider = name
+	}
+}
+
+func (r *fetchReport) setMissing(missing []missingValue) {
+	if r != nil && len(missing) > 0 {
+		r.Missing = append([]missingValue(nil), missing...)
+		sortMissing(r.Missing)
+	}
+}
+
+// addFile records the difference between an env file's old and new keys
+func (r *fetchReport) addFile(path string, env config.Environment, final, existing map[string]string, mappings map[string]config.Mapping) {
+	if r == nil {
+		return
+	}
+	f := fileReport{
+		Path:        path,
+		Environment: env,
+		Keys:        []string{},
+		Added:       []string{},
+		Changed:     []string{},
+		Removed:     []string{},
+		Unmapped:    envwriter.UnmappedKeys(existing, mappings),
+	}
+	if f.Unmapped == nil {
+		f.Unmapped = []string{}
+	}
+	for key, value := range final {
+		f.Keys = append(f.Keys, key)
+		old, ok := existing[key]
+		switch {
+		case !ok:
+			f.Added = append(f.Added, key)
+		case old != value:
+			f.Changed = append(f.Changed, key)
+		}
+	}
+	for key := range existing {
+		if _, ok := final[key]; !ok {
+			f.Removed = append(f.Removed, key)
+		}
+	}
+	sort.Strings(f.Keys)
+	sort.Strings(f.Added)
+	sort.Strings(f.Changed)
+	sort.Strings(f.Removed)
+	r.Files = append(r.Files, f)
+}
+
+// write finishes the report with the run's outcome and writes it atomically
+func (r *fetchReport) write(start time.Time, runErr error) error {
+	if r == nil {
+		return nil
+	}
+	var exitErr *exitCodeError
+	if errors.As(runErr, &exitErr) && exitErr.msg == "" {
+		// --diff-exit signals changed values, not a failed run
+		runErr = nil
+	}
+	r.Timestamp = start.UTC()
+	r.DurationMS = time.Since(start).Milliseconds()
+	r.Success = runErr == nil
+	if runErr != nil {
+		r.Error = runErr.Error()
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), ".report-tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // Clean up on any error
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	tmp.Close()
+
+	if err := os.Rename(tmpPath, r.path); err != nil {
+		return fmt.Errorf("failed to write report %s: %w", r.path, err)
+	}
+	return nil
+}
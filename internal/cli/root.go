@@ -1,12 +1,17 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"runtime"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/JayDubyaEey/yeet/internal/config"
 	"github.com/JayDubyaEey/yeet/internal/ui"
 	"github.com/JayDubyaEey/yeet/pkg/version"
 )
@@ -16,8 +21,29 @@ var (
 	vaultOverride string
 	noColor       bool
 	verbose       bool
+	trace         bool
+	themeName     string
+	assumeYes     bool
+	providerName  string
+	providerCmd   string
+	deadline      time.Duration
+	noLoginCheck  bool
+	chdir         string
+	vaultDNS      string
+
+	// concurrency bounds how many secrets are fetched, checked or set at once
+	concurrency = defaultConcurrency
+
+	// deadlineCtx is the root context once --deadline applies; cancelDeadline releases it
+	deadlineCtx    context.Context
+	cancelDeadline context.CancelFunc
+
+	// cleanupRemoteConfig removes the temp copy of a remote --config
+	cleanupRemoteConfig = func() {}
 )
 
+const defaultConcurrency = 6
+
 func newRootCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:           "yeet",
@@ -25,31 +51,129 @@ func newRootCmd() *cobra.Command {
 		Long:          "Yeet pulls secrets from Azure Key Vault and generates .env and docker.env for local dev and docker.",
 		SilenceUsage:  true,
 		SilenceErrors: true,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := ui.SetTheme(themeName); err != nil {
+				return err
+			}
 			ui.Setup(noColor, verbose)
+			if err := changeDir(chdir); err != nil {
+				return err
+			}
+			if deadline > 0 {
+				deadlineCtx, cancelDeadline = context.WithTimeout(cmd.Context(), deadline)
+				cmd.SetContext(deadlineCtx)
+			}
+			if concurrency < 1 {
+				return fmt.Errorf("--concurrency must be at least 1")
+			}
+			if vaultDNS != "" {
+				if err := config.ValidateDNSSuffix(vaultDNS); err != nil {
+					return fmt.Errorf("--vault-dns-suffix: %w", err)
+				}
+			}
+			if err := checkProviderFlags(); err != nil {
+				return err
+			}
+			if err := checkTrimFlag(); err != nil {
+				return err
+			}
+			cleanup, err := fetchRemoteConfig(cmd.Context())
+			if err != nil {
+				return err
+			}
+			cleanupRemoteConfig = cleanup
+			return nil
 		},
 	}
 
-	cmd.PersistentFlags().StringVar(&configPath, "config", "env.config.json", "Path to env configuration file")
+	cmd.PersistentFlags().StringVar(&configPath, "config", "env.config.json", "Path or https:// URL of the env configuration file")
+	cmd.PersistentFlags().StringVarP(&chdir, "chdir", "C", "", "Run as if started in this directory (config, deployment and output paths are resolved from it)")
+	cmd.PersistentFlags().BoolVar(&strictConfig, "strict-config", false, "Fail instead of warning when the config file has fields the config format does not define")
 	cmd.PersistentFlags().StringVar(&vaultOverride, "vault", "", "Override Key Vault name from config")
+	cmd.PersistentFlags().StringVar(&vaultDNS, "vault-dns-suffix", "", "Key Vault DNS suffix for clouds with custom vault DNS, e.g. Azure Stack (default from config, else "+config.DefaultVaultDNSSuffix+")")
 	cmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output")
 	cmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
+	cmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "Automatically confirm prompts for commands that modify state")
+	cmd.PersistentFlags().StringVar(&themeName, "theme", "default", "Output theme ("+strings.Join(ui.ThemeNames(), "|")+")")
+	cmd.PersistentFlags().BoolVar(&trace, "trace", false, "Print per-secret fetch timings")
+	cmd.PersistentFlags().IntVar(&concurrency, "concurrency", defaultConcurrency, "Number of secrets to fetch, check or set at once")
+	cmd.PersistentFlags().DurationVar(&deadline, "deadline", 0, "Cancel the command, including any Azure CLI calls, after this long (e.g. 5m; 0 means no limit)")
+	cmd.PersistentFlags().StringVar(&providerName, "provider", "", "Secret backend ("+providerAzCLI+"|"+providerAWS+"|"+providerExec+"; default from the config's provider, else "+providerAzCLI+")")
+	cmd.PersistentFlags().StringVar(&trimWhitespace, "trim-whitespace", string(config.TrimNone), "Trim whitespace from fetched secret values (none|trailing|all); a mapping's trim setting takes precedence")
+	cmd.PersistentFlags().BoolVar(&noLoginCheck, "no-login-check", false, "Skip the provider's login check when auth is handled externally; auth errors then come from the secret calls")
+	cmd.PersistentFlags().StringVar(&providerCmd, "provider-cmd", "", "Command implementing the exec provider contract (with --provider exec)")
 
 	cmd.Version = version.Version + fmt.Sprintf(" (%s/%s)", runtime.GOOS, runtime.GOARCH)
 
+	cmd.AddCommand(newLoginCmd())
+	cmd.AddCommand(newStatusCmd())
 	cmd.AddCommand(newFetchCmd())
 	cmd.AddCommand(newRunCmd())
 	cmd.AddCommand(newValidateCmd())
 	cmd.AddCommand(newListCmd())
 	cmd.AddCommand(newCompareCmd())
+	cmd.AddCommand(newGenDeploymentEnvCmd())
+	cmd.AddCommand(newComposeBlockCmd())
+	cmd.AddCommand(newDumpCmd())
+	cmd.AddCommand(newDiffEnvsCmd())
+	cmd.AddCommand(newConfigCmd())
+	cmd.AddCommand(newSetCmd())
+	cmd.AddCommand(newExportCmd())
+	cmd.AddCommand(newHookCmd())
+	cmd.AddCommand(newGetCmd())
 
 	return cmd
 }
 
+// exitCodeError makes Execute exit with a specific code; an empty message
+// means the command already reported what happened
+type exitCodeError struct {
+	code int
+	msg  string
+}
+
+func (e *exitCodeError) Error() string {
+	return e.msg
+}
+
 // Execute runs the CLI
 func Execute() {
-	if err := newRootCmd().Execute(); err != nil {
+	err := newRootCmd().Execute()
+	cleanupRemoteConfig()
+	if cancelDeadline != nil {
+		if err != nil && errors.Is(deadlineCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("deadline of %s exceeded: %w", deadline, err)
+		}
+		cancelDeadline()
+	}
+	if err != nil {
+		var exitErr *exitCodeError
+		if errors.As(err, &exitErr) {
+			if exitErr.msg != "" {
+				ui.Error("%s", exitErr.msg)
+			}
+			os.Exit(exitErr.code)
+		}
 		ui.Error("%s", err.Error())
 		os.Exit(1)
 	}
 }
+
+// changeDir switches to the --chdir directory, if one was given
+func changeDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("--chdir: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("--chdir: %s is not a directory", dir)
+	}
+	if err := os.Chdir(dir); err != nil {
+		return fmt.Errorf("--chdir: %w", err)
+	}
+	ui.Info("working in %s", dir)
+	return nil
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+	"github.com/JayDubyaEey/yeet/internal/provider"
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+// checkValueRules returns a "KEY (env): problem" entry for every fetched
+// value that breaks its mapping's validate rules
+func checkValueRules(results []secretResult, cfg *config.Config) []string {
+	var violations []string
+	for _, r := range results {
+		mapping := cfg.Mappings[r.key]
+		if err := mapping.Rules.Check(r.value); err != nil {
+			violations = append(violations, fmt.Sprintf("%s (%s): %v", r.key, r.environment, err))
+		}
+	}
+	sort.Strings(violations)
+	return violations
+}
+
+// checkSecretRules fetches the values of mappings that have validate rules or
+// a split and checks them; secrets that do not exist are left to the
+// existence check
+func checkSecretRules(ctx context.Context, prov provider.Provider, vault string, cfg *config.Config) ([]string, error) {
+	values := make(map[string]string)
+	var results []secretResult
+	var splitErrors []string
+
+	for key, mapping := range cfg.Mappings {
+		if mapping.Rules == nil && mapping.Split == nil {
+			continue
+		}
+		for _, env := range []config.Environment{config.EnvLocal, config.EnvDocker} {
+			spec := cfg.ValueSpec(mapping, env)
+			if spec == nil {
+				continue
+			}
+			if spec.IsLiteral() {
+				results = append(results, secretResult{key: key, environment: env, value: spec.Value})
+				continue
+			}
+
+			value, ok := values[spec.Value]
+			if !ok {
+				val, err := prov.GetSecret(ctx, vault, spec.Value)
+				if err != nil {
+					if provider.IsNotFound(err) {
+						continue
+					}
+					return nil, err
+				}
+				values[spec.Value] = val
+				value = val
+			}
+			value, err := mappedSecretValue(mapping, key, env, value)
+			if err != nil {
+				splitErrors = append(splitErrors, err.Error())
+				continue
+			}
+			results = append(results, secretResult{key: key, environment: env, value: value})
+		}
+	}
+
+	violations := append(checkValueRules(results, cfg), splitErrors...)
+	sort.Strings(violations)
+	return violations, nil
+}
+
+// reportRuleViolations prints violations and returns an error summarising them
+func reportRuleViolations(violations []string) error {
+	ui.Error("%d values failed validation rules:", len(violations))
+	for _, v := range violations {
+		ui.Error("  - %s", v)
+	}
+	return fmt.Errorf("%d values failed validation rules", len(violations))
+}
@@ -3,18 +3,25 @@ package cli
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/term"
 
 	"github.com/JayDubyaEey/yeet/internal/config"
-	"github.com/JayDubyaEey/yeet/internal/provider/azcli"
+	"github.com/JayDubyaEey/yeet/internal/envwriter"
+	"github.com/JayDubyaEey/yeet/internal/provider"
 	"github.com/JayDubyaEey/yeet/internal/ui"
 )
 
@@ -22,8 +29,34 @@ var (
 	loadEnvFile bool
 	envFilePath string
 	targetEnv   string
+	teePath     string
+	teeAppend   bool
+	interactive bool
+	expandEnv   bool
+
+	retryCount   int
+	retryOnExit  []int
+	retryRefetch bool
+	workDir      string
+
+	sourceCmd         string
+	sourceCmdOverride bool
+	strictEnvFile     bool
+	bundlePath        string
+	ignoreCase        bool
+	envJSON           string
+	rawMissing        bool
+	envSection        string
+	envFileOutput     string
+	showSecrets       bool
+	allowMissing      bool
+	preExec           string
+	postExec          string
 )
 
+// overrideKeyRegex is what --strict-env-file accepts as a key
+var overrideKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
 func newRunCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "run [command...]",
@@ -35,50 +68,151 @@ Examples:
   yeet run --env docker docker-compose up       # Run with docker environment
   yeet run --vault my-vault npm start           # Override vault
   yeet run -e docker -- docker-compose up       # Use docker environment
-  yeet run --load-env -- npm start              # Load .env file for overrides`,
+  yeet run --load-env -- npm start              # Load .env file for overrides
+  yeet run --tee run.log -- make test           # Mirror output to run.log
+  yeet run --workdir services/api -- npm start  # Run in a subdirectory
+  yeet run --retry 2 --retry-on-exit 75 -- make it   # Rerun when the child exits 75
+  yeet run --on-secret-change restart -- npm start   # Restart when a secret changes`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runWithSecrets(cmd.Context(), args)
+			return runWithSecrets(cmd.Context(), args, cmd.Flags().Changed("env"))
 		},
 	}
 
 	cmd.Flags().BoolVarP(&loadEnvFile, "load-env", "l", false, "Load .env file for local overrides")
 	cmd.Flags().StringVar(&envFilePath, "env-file", ".env", "Path to env file to load (only used with --load-env)")
-	cmd.Flags().StringVarP(&targetEnv, "env", "e", "local", "Target environment (local|docker)")
+	cmd.Flags().StringVar(&envSection, "section", "", "Read only this section of a combined --env-file written by 'fetch --combine' (local|docker)")
+	cmd.Flags().StringSliceVar(&envPassthrough, "env-passthrough", nil, "Only inherit parent variables matching these comma-separated globs (e.g. 'PATH,HOME,AWS_*'); secrets are added on top")
+	cmd.Flags().StringSliceVar(&envAppend, "env-append", nil, "Append the values of these comma-separated keys to the inherited ones (e.g. 'PATH,LD_LIBRARY_PATH') instead of replacing them")
+	cmd.Flags().StringVar(&envSeparator, "env-separator", string(os.PathListSeparator), "Separator placed between the inherited and appended value for --env-append keys")
+	cmd.Flags().BoolVar(&allowMissing, "allow-missing", false, "Warn about missing values and run the command with the ones that resolved instead of aborting")
+	cmd.Flags().StringVar(&envFileOutput, "env-file-output", "", "Before running, write the environment the command receives to this file (values masked unless --show-secrets)")
+	cmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Write real values to --env-file-output instead of masking them")
+	cmd.Flags().StringVar(&envJSON, "env-json", "", "JSON object of KEY:value overrides applied after all other sources")
+	cmd.Flags().BoolVar(&ignoreCase, "ignore-case", false, "Match --load-env keys to resolved keys case-insensitively, using the resolved key's casing")
+	cmd.Flags().BoolVar(&strictEnvFile, "strict-env-file", false, "Fail on malformed lines in the --load-env file instead of skipping them")
+	cmd.Flags().StringVarP(&targetEnv, "env", "e", "local", "Target environment (local|docker); defaults to the config's defaultEnvironment")
+	_ = cmd.RegisterFlagCompletionFunc("env", completeEnvironments)
+	cmd.Flags().StringVar(&teePath, "tee", "", "Mirror the command's stdout and stderr to this file")
+	cmd.Flags().BoolVar(&teeAppend, "tee-append", false, "Append to the --tee file instead of truncating it")
+	cmd.Flags().BoolVar(&expandEnv, "dotenv-expand", false, "Expand ${VAR} and ${VAR:-default} references in --load-env values")
+	cmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Prompt for missing secret values when running in a terminal")
+	cmd.Flags().BoolVar(&rawMissing, "raw", false, "Report missing values as JSON on stdout instead of running the command")
+	cmd.Flags().StringVar(&sourceCmd, "source-cmd", "", "Shell command whose dotenv/export output is merged into the environment")
+	cmd.Flags().BoolVar(&sourceCmdOverride, "source-cmd-override", false, "Let --source-cmd values take precedence over vault values")
+	cmd.Flags().StringVar(&bundlePath, "vault-file", "", "Read values from an encrypted bundle written by 'fetch --bundle-out' instead of Key Vault")
+	cmd.Flags().StringVar(&bundlePassphraseEnv, "passphrase-env", defaultPassphraseEnv, "Environment variable holding the bundle passphrase")
+	cmd.Flags().StringVar(&preExec, "preexec", "", "Shell command run with the same secrets before the main command; the main command is skipped if it fails")
+	cmd.Flags().StringVar(&postExec, "postexec", "", "Shell command run with the same secrets after the main command, even if it fails")
+	cmd.Flags().StringVar(&workDir, "workdir", "", "Run the command in this directory")
+	cmd.Flags().IntVar(&retryCount, "retry", 0, "Rerun the command up to N more times if it fails")
+	cmd.Flags().IntSliceVar(&retryOnExit, "retry-on-exit", nil, "Only retry on these exit codes (default: any non-zero exit)")
+	cmd.Flags().BoolVar(&retryRefetch, "retry-refetch", false, "Fetch secrets again before each retry")
+	cmd.Flags().StringVar(&onSecretChange, "on-secret-change", "", "While the command runs, poll the vault and restart or signal it when a value changes (restart|signal)")
+	cmd.Flags().DurationVar(&secretPollInterval, "secret-poll-interval", time.Minute, "How often --on-secret-change checks the vault")
+	cmd.Flags().StringVar(&reloadSignal, "reload-signal", "HUP", "Signal sent by --on-secret-change signal")
+	cmd.Flags().DurationVar(&stopGrace, "stop-grace", 10*time.Second, "How long a restarted command may take to exit after SIGTERM before it is killed")
 
 	return cmd
 }
 
-func runWithSecrets(ctx context.Context, args []string) error {
-	// Load configuration and determine vault
-	cfg, vault, err := loadRunConfig()
-	if err != nil {
+func runWithSecrets(ctx context.Context, args []string, envFlagSet bool) error {
+	if err := checkWorkDir(workDir); err != nil {
 		return err
 	}
+	if err := checkWatchFlags(); err != nil {
+		return err
+	}
+	if err := checkPassthroughPatterns(envPassthrough); err != nil {
+		return err
+	}
+
+	// Base values come from an encrypted bundle or from Key Vault
+	var fetchBase func() (map[string]string, error)
+	nameRegex := regexp.MustCompile(config.DefaultNamePattern)
+	if bundlePath != "" {
+		fetchBase = func() (map[string]string, error) {
+			return loadBundleEnv(bundlePath, targetEnv)
+		}
+	} else {
+		// Load configuration and determine vault
+		cfg, vault, err := loadRunConfig()
+		if err != nil {
+			return err
+		}
+		targetEnv = defaultEnvironment(cfg, targetEnv, envFlagSet)
+		// Reject an unknown --env before logging in or fetching anything
+		if _, err := parseTargetEnvironment(); err != nil {
+			return err
+		}
+		if nameRegex, err = cfg.NameRegexp(); err != nil {
+			return err
+		}
+
+		// Initialize provider and ensure logged in
+		prov := newProvider(cfg)
+		if err := ensureLoggedIn(ctx, prov); err != nil {
+			return err
+		}
+
+		if _, err := expandIncludes(ctx, prov, vault, cfg); err != nil {
+			return err
+		}
+
+		fetchBase = func() (map[string]string, error) {
+			return fetchAndPrepareSecrets(ctx, cfg, vault, prov)
+		}
+	}
 
-	// Initialize provider and ensure logged in
-	prov := azcli.NewDefault()
-	if err := prov.EnsureLoggedIn(ctx); err != nil {
-		return fmt.Errorf("not logged in to Azure CLI: %w (run: yeet login)", err)
+	// Parse inline overrides up front so a typo fails before any fetch
+	var jsonOverrides map[string]string
+	if envJSON != "" {
+		var err error
+		if jsonOverrides, err = parseEnvJSON(envJSON, nameRegex); err != nil {
+			return err
+		}
+	}
+
+	// Resolve values and apply local overrides if requested
+	resolve := func() (map[string]string, error) {
+		envVars, err := fetchBase()
+		if err != nil {
+			return nil, err
+		}
+		if sourceCmd != "" {
+			if err := applySourceCmdEnv(ctx, envVars, sourceCmd, sourceCmdOverride); err != nil {
+				return nil, err
+			}
+		}
+		if loadEnvFile {
+			if err := applyEnvFileOverrides(envVars, envFilePath); err != nil {
+				return nil, err
+			}
+		}
+		for key, value := range jsonOverrides {
+			envVars[key] = value
+		}
+		appendInherited(envVars)
+		return envVars, nil
 	}
 
-	// Fetch secrets from Key Vault
-	envVars, err := fetchAndPrepareSecrets(ctx, cfg, vault, prov)
+	envVars, err := resolve()
 	if err != nil {
 		return err
 	}
 
-	// Apply local overrides if requested
-	if loadEnvFile {
-		applyEnvFileOverrides(envVars, envFilePath)
+	if envFileOutput != "" {
+		if err := writeEnvSnapshot(envFileOutput, args, envVars, showSecrets); err != nil {
+			return err
+		}
 	}
 
 	// Execute command with secrets
-	return executeCommandWithEnv(ctx, args, envVars)
+	return executeCommandWithEnv(ctx, args, envVars, resolve)
 }
 
 func loadRunConfig() (*config.Config, string, error) {
-	cfg, err := config.Load(configPath)
+	cfg, err := loadConfig()
 	if err != nil {
 		return nil, "", err
 	}
@@ -91,7 +225,7 @@ func loadRunConfig() (*config.Config, string, error) {
 	return cfg, vault, nil
 }
 
-func fetchAndPrepareSecrets(ctx context.Context, cfg *config.Config, vault string, prov *azcli.Provider) (map[string]string, error) {
+func fetchAndPrepareSecrets(ctx context.Context, cfg *config.Config, vault string, prov provider.Provider) (map[string]string, error) {
 	ui.Info("fetching secrets from vault: %s", vault)
 
 	envVars, err := fetchSecretsAsEnv(ctx, cfg, vault, prov)
@@ -103,24 +237,150 @@ func fetchAndPrepareSecrets(ctx context.Context, cfg *config.Config, vault strin
 	return envVars, nil
 }
 
-func applyEnvFileOverrides(envVars map[string]string, envFilePath string) {
-	overrides, err := loadEnvOverrides(envFilePath)
+// applyEnvFileOverrides applies --load-env values; problems with the file are
+// only warnings unless --strict-env-file is set
+func applyEnvFileOverrides(envVars map[string]string, envFilePath string) error {
+	var (
+		overrides map[string]string
+		order     []string
+		err       error
+	)
+	if envSection != "" {
+		overrides, order, err = loadEnvSection(envFilePath, envSection, strictEnvFile)
+	} else {
+		overrides, order, err = loadEnvOverrides(envFilePath, strictEnvFile)
+	}
 	if err != nil {
+		if strictEnvFile {
+			return err
+		}
 		ui.Warn("could not load env file %s: %v", envFilePath, err)
-		return
+		return nil
+	}
+
+	var canonical map[string]string
+	if ignoreCase {
+		canonical = canonicalKeys(envVars)
 	}
 
-	// Apply overrides
-	for key, value := range overrides {
+	// Apply overrides in file order so expansion can see earlier ones
+	for _, key := range order {
+		value := overrides[key]
+		if name, ok := canonical[strings.ToLower(key)]; ok && name != key {
+			ui.Info("matching %s from %s to %s", key, envFilePath, name)
+			key = name
+		}
+		if expandEnv {
+			value = expandValue(value, envVars)
+		}
 		if _, exists := envVars[key]; exists {
 			ui.Info("overriding %s from %s", key, envFilePath)
 		}
 		envVars[key] = value
 	}
 	ui.Success("loaded %d overrides from %s", len(overrides), envFilePath)
+	return nil
 }
 
-func executeCommandWithEnv(ctx context.Context, args []string, envVars map[string]string) error {
+// parseEnvJSON parses --env-json as an object of string values whose keys
+// must match nameRegex
+func parseEnvJSON(raw string, nameRegex *regexp.Regexp) (map[string]string, error) {
+	var values map[string]string
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		return nil, fmt.Errorf("invalid --env-json: must be a JSON object of string values: %w", err)
+	}
+	for key := range values {
+		if !nameRegex.MatchString(key) {
+			return nil, fmt.Errorf("invalid --env-json key %q (must match %s)", key, nameRegex.String())
+		}
+	}
+	ui.Info("loaded %d overrides from --env-json", len(values))
+	return values, nil
+}
+
+// canonicalKeys maps each lower-cased key in envVars to its original casing,
+// leaving out keys that differ only by case since they cannot be told apart
+func canonicalKeys(envVars map[string]string) map[string]string {
+	canonical := make(map[string]string, len(envVars))
+	ambiguous := make(map[string]bool)
+	for key := range envVars {
+		lower := strings.ToLower(key)
+		if _, seen := canonical[lower]; seen {
+			ambiguous[lower] = true
+		}
+		canonical[lower] = key
+	}
+	for lower := range ambiguous {
+		delete(canonical, lower)
+	}
+	return canonical
+}
+
+// executeCommandWithEnv runs the command, rerunning it according to the
+// --retry flags; resolve is used to refetch secrets when --retry-refetch is set.
+// --preexec and --postexec run around it with the same environment and output.
+func executeCommandWithEnv(ctx context.Context, args []string, envVars map[string]string, resolve func() (map[string]string, error)) (err error) {
+	stdout, stderr := io.Writer(os.Stdout), io.Writer(os.Stderr)
+
+	// Mirror output to a log file if requested
+	if teePath != "" {
+		teeFile, err := openTeeFile(teePath, teeAppend)
+		if err != nil {
+			return err
+		}
+		defer teeFile.Close()
+		stdout = io.MultiWriter(os.Stdout, teeFile)
+		stderr = io.MultiWriter(os.Stderr, teeFile)
+		ui.Info("mirroring output to %s", teePath)
+	}
+
+	if preExec != "" {
+		if err := runExecHook(ctx, "--preexec", preExec, envVars, stdout, stderr); err != nil {
+			return err
+		}
+	}
+	if postExec != "" {
+		defer func() {
+			// Still runs once --deadline has stopped the main command
+			hookErr := runExecHook(context.WithoutCancel(ctx), "--postexec", postExec, envVars, stdout, stderr)
+			switch {
+			case hookErr == nil:
+			case err == nil:
+				err = hookErr
+			default:
+				// Keep the main command's failure and exit code
+				ui.Warn("%v", hookErr)
+			}
+		}()
+	}
+
+	if onSecretChange != "" {
+		return runWatched(ctx, args, envVars, resolve, stdout, stderr)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := runChild(ctx, args, envVars, stdout, stderr)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			// The command was killed because --deadline expired
+			return fmt.Errorf("command stopped: %w", ctx.Err())
+		}
+		if attempt >= retryCount || !shouldRetry(err) {
+			return handleCommandError(err)
+		}
+
+		ui.Warn("command failed (%v), retrying (%d/%d)", err, attempt+1, retryCount)
+		if retryRefetch {
+			if envVars, err = resolve(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func runChild(ctx context.Context, args []string, envVars map[string]string, stdout, stderr io.Writer) error {
 	cmdName := args[0]
 	cmdArgs := args[1:]
 
@@ -130,59 +390,134 @@ func executeCommandWithEnv(ctx context.Context, args []string, envVars map[strin
 	cmd := exec.CommandContext(ctx, cmdName, cmdArgs...)
 
 	// Set up environment
-	cmd.Env = os.Environ() // Start with current environment
+	cmd.Env = parentEnv() // Start with the inherited environment
 	for key, value := range envVars {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
 	}
 
+	cmd.Dir = workDir
+
 	// Connect stdio
 	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	return cmd.Run()
+}
 
-	// Run the command
-	if err := cmd.Run(); err != nil {
-		return handleCommandError(err)
+// shouldRetry reports whether a failed run matches --retry-on-exit; failures
+// to start the command at all are never retried
+func shouldRetry(err error) bool {
+	exitError, ok := err.(*exec.ExitError)
+	if !ok {
+		return false
+	}
+	if len(retryOnExit) == 0 {
+		return true
+	}
+	code := exitError.ExitCode()
+	for _, c := range retryOnExit {
+		if c == code {
+			return true
+		}
 	}
+	return false
+}
 
+// checkWorkDir validates --workdir before any secrets are fetched
+func checkWorkDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("invalid --workdir: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("invalid --workdir: %s is not a directory", dir)
+	}
 	return nil
 }
 
+func openTeeFile(path string, appendMode bool) (*os.File, error) {
+	flags := os.O_CREATE | os.O_WRONLY
+	if appendMode {
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+	}
+	// The child's output often echoes injected secrets
+	f, err := os.OpenFile(path, flags, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tee file %s: %w", path, err)
+	}
+	if !appendMode {
+		if err := f.Chmod(0600); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to set permissions on tee file %s: %w", path, err)
+		}
+	}
+	return f, nil
+}
+
 func handleCommandError(err error) error {
 	// Try to get the exit code
 	if exitError, ok := err.(*exec.ExitError); ok {
 		if status, ok := exitError.Sys().(syscall.WaitStatus); ok {
-			os.Exit(status.ExitStatus())
+			// Exit with the command's code once deferred work such as
+			// --postexec has run
+			return &exitCodeError{code: status.ExitStatus()}
 		}
 	}
 	return fmt.Errorf("command failed: %w", err)
 }
 
-func fetchSecretsAsEnv(ctx context.Context, cfg *config.Config, vault string, prov *azcli.Provider) (map[string]string, error) {
+func fetchSecretsAsEnv(ctx context.Context, cfg *config.Config, vault string, prov provider.Provider) (map[string]string, error) {
 	// Parse target environment
 	env, err := parseTargetEnvironment()
 	if err != nil {
 		return nil, err
 	}
+	return resolveEnvVars(ctx, cfg, vault, prov, env)
+}
 
+// resolveEnvVars fetches the secrets env needs and returns its full variable map
+func resolveEnvVars(ctx context.Context, cfg *config.Config, vault string, prov provider.Provider, env config.Environment) (map[string]string, error) {
 	envVars := make(map[string]string)
-	missing := make([]string, 0)
+	missing := make([]missingValue, 0)
 	secretCache := make(map[string]string) // Cache to avoid duplicate fetches
 
 	g, gctx := errgroup.WithContext(ctx)
-	sem := make(chan struct{}, 6) // concurrency limit
+	sem := make(chan struct{}, concurrency)
 	var mu sync.Mutex
 
 	// First pass: collect all unique keyvault secrets we need
 	secretsToFetch := collectUniqueSecrets(cfg, env)
 
 	// Fetch all required secrets
-	if err := fetchRequiredSecrets(gctx, g, sem, prov, vault, secretsToFetch, secretCache, &missing, &mu); err != nil {
+	tracer := newFetchTracer()
+	err := fetchRequiredSecrets(gctx, g, sem, prov, vault, secretsToFetch, secretCache, &missing, &mu, tracer)
+	tracer.report()
+	if err != nil {
 		return nil, err
 	}
 
 	// Second pass: build environment variables
-	buildEnvironmentVariables(cfg, env, secretCache, envVars, &missing)
+	if err := buildEnvironmentVariables(cfg, env, secretCache, envVars, &missing); err != nil {
+		return nil, err
+	}
+
+	if len(missing) > 0 && interactive && isTerminal(os.Stdin) {
+		if err := promptMissingValues(cfg, env, envVars); err != nil {
+			return nil, err
+		}
+		missing = missing[:0]
+	}
+
+	if len(missing) > 0 && allowMissing {
+		warnMissingValues(missing, env)
+		missing = missing[:0]
+	}
 
 	if len(missing) > 0 {
 		return nil, reportMissingValues(missing, env)
@@ -192,47 +527,72 @@ func fetchSecretsAsEnv(ctx context.Context, cfg *config.Config, vault string, pr
 }
 
 func parseTargetEnvironment() (config.Environment, error) {
-	switch targetEnv {
+	return parseEnvironment(targetEnv)
+}
+
+// defaultEnvironment returns the config's defaultEnvironment unless the
+// --env flag was given explicitly
+func defaultEnvironment(cfg *config.Config, flagValue string, flagSet bool) string {
+	if flagSet || cfg.DefaultEnvironment == "" {
+		return flagValue
+	}
+	return string(cfg.DefaultEnvironment)
+}
+
+func parseEnvironment(name string) (config.Environment, error) {
+	switch name {
 	case "local":
 		return config.EnvLocal, nil
 	case "docker":
 		return config.EnvDocker, nil
 	default:
-		return "", fmt.Errorf("invalid environment %q: must be 'local' or 'docker'", targetEnv)
+		return "", fmt.Errorf("invalid environment %q: must be 'local' or 'docker'", name)
 	}
 }
 
+// completeEnvironments completes --env with the environments a config can define
+func completeEnvironments(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return []string{string(config.EnvLocal), string(config.EnvDocker)}, cobra.ShellCompDirectiveNoFileComp
+}
+
+// collectUniqueSecrets returns the set of Key Vault secrets env reads, so a
+// secret referenced by several keys is fetched only once
 func collectUniqueSecrets(cfg *config.Config, env config.Environment) map[string]bool {
 	secretsToFetch := make(map[string]bool)
 	for _, mapping := range cfg.Mappings {
-		if spec := mapping.GetValueSpec(env); spec != nil && spec.IsKeyvaultSecret() {
+		if spec := cfg.ValueSpec(mapping, env); spec != nil && spec.IsKeyvaultSecret() {
 			secretsToFetch[spec.Value] = true
 		}
 	}
 	return secretsToFetch
 }
 
-func fetchRequiredSecrets(gctx context.Context, g *errgroup.Group, sem chan struct{}, prov *azcli.Provider, vault string, secretsToFetch map[string]bool, secretCache map[string]string, missing *[]string, mu *sync.Mutex) error {
+func fetchRequiredSecrets(gctx context.Context, g *errgroup.Group, sem chan struct{}, prov provider.Provider, vault string, secretsToFetch map[string]bool, secretCache map[string]string, missing *[]missingValue, mu *sync.Mutex, tracer *fetchTracer) error {
 	for secretName := range secretsToFetch {
 		secretName := secretName
 		sem <- struct{}{}
 		g.Go(func() error {
 			defer func() { <-sem }()
-			return fetchSingleSecret(gctx, prov, vault, secretName, secretCache, missing, mu)
+			return fetchSingleSecret(gctx, prov, vault, secretName, secretCache, missing, mu, tracer)
 		})
 	}
 	return g.Wait()
 }
 
-func fetchSingleSecret(ctx context.Context, prov *azcli.Provider, vault, secretName string, secretCache map[string]string, missing *[]string, mu *sync.Mutex) error {
+func fetchSingleSecret(ctx context.Context, prov provider.Provider, vault, secretName string, secretCache map[string]string, missing *[]missingValue, mu *sync.Mutex, tracer *fetchTracer) error {
+	start := time.Now()
 	val, err := prov.GetSecret(ctx, vault, secretName)
+	tracer.record(secretName, time.Since(start))
 	if err != nil {
-		if azcli.IsNotFound(err) {
+		if provider.IsNotFound(err) {
 			mu.Lock()
-			*missing = append(*missing, fmt.Sprintf("secret: %s", secretName))
+			*missing = append(*missing, missingValue{Secret: secretName})
 			mu.Unlock()
 			return nil
 		}
+		if provider.IsVaultNotFound(err) || provider.IsAuth(err) {
+			return err // vault-wide problem, not specific to this secret
+		}
 		return fmt.Errorf("failed to get secret %s: %w", secretName, err)
 	}
 
@@ -242,26 +602,90 @@ func fetchSingleSecret(ctx context.Context, prov *azcli.Provider, vault, secretN
 	return nil
 }
 
-func buildEnvironmentVariables(cfg *config.Config, env config.Environment, secretCache map[string]string, envVars map[string]string, missing *[]string) {
+func buildEnvironmentVariables(cfg *config.Config, env config.Environment, secretCache map[string]string, envVars map[string]string, missing *[]missingValue) error {
 	for envKey, mapping := range cfg.Mappings {
-		spec := mapping.GetValueSpec(env)
+		spec := cfg.ValueSpec(mapping, env)
 		if spec == nil {
 			continue // No value for this environment
 		}
 
+		name := mapping.OutputName(envKey)
 		if spec.IsKeyvaultSecret() {
 			if val, exists := secretCache[spec.Value]; exists {
-				envVars[envKey] = val
+				value, err := mappedSecretValue(mapping, envKey, env, val)
+				if err != nil {
+					return err
+				}
+				envVars[name] = value
 			} else {
-				*missing = append(*missing, fmt.Sprintf("%s (%s) -> %s", envKey, env, spec.Value))
+				*missing = append(*missing, missingValue{Key: envKey, Environment: env, Secret: spec.Value})
 			}
 		} else if spec.IsLiteral() {
-			envVars[envKey] = spec.Value
+			envVars[name] = spec.Value
+		}
+	}
+	return nil
+}
+
+// promptMissingValues asks the user for a value for every mapping that could not
+// be resolved; entered values are only used for this run
+func promptMissingValues(cfg *config.Config, env config.Environment, envVars map[string]string) error {
+	keys := make([]string, 0)
+	for envKey, mapping := range cfg.Mappings {
+		name := mapping.OutputName(envKey)
+		if _, resolved := envVars[name]; resolved {
+			continue
+		}
+		if cfg.ValueSpec(mapping, env) != nil {
+			keys = append(keys, name)
 		}
 	}
+	sort.Strings(keys)
+
+	reader := bufio.NewReader(os.Stdin)
+	for _, envKey := range keys {
+		fmt.Fprintf(os.Stderr, "enter a value for %s (will not be stored): ", envKey)
+		value, err := readSecretLine(reader)
+		if err != nil {
+			return fmt.Errorf("failed to read value for %s: %w", envKey, err)
+		}
+		envVars[envKey] = value
+	}
+	return nil
+}
+
+// readSecretLine reads one line from stdin, without echo when it is a terminal
+// so the value stays out of scrollback and screen recordings
+func readSecretLine(reader *bufio.Reader) (string, error) {
+	if isTerminal(os.Stdin) {
+		value, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		return string(value), err
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
 }
 
-func reportMissingValues(missing []string, env config.Environment) error {
+// isTerminal reports whether f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func reportMissingValues(missing []missingValue, env config.Environment) error {
+	sortMissing(missing)
+	if rawMissing {
+		if err := outputJSON(missingReport{Environment: env, Missing: missing}); err != nil {
+			return err
+		}
+		return fmt.Errorf("one or more values are missing")
+	}
 	ui.Error("missing %d values for environment %s:", len(missing), env)
 	for _, m := range missing {
 		ui.Error("  - %s", m)
@@ -269,32 +693,71 @@ func reportMissingValues(missing []string, env config.Environment) error {
 	return fmt.Errorf("one or more values are missing")
 }
 
-func loadEnvOverrides(path string) (map[string]string, error) {
+// warnMissingValues lists values left unset by --allow-missing
+func warnMissingValues(missing []missingValue, env config.Environment) {
+	sortMissing(missing)
+	ui.Warn("running without %d missing values for environment %s:", len(missing), env)
+	for _, m := range missing {
+		ui.Warn("  - %s", m)
+	}
+}
+
+// loadEnvOverrides parses an env file and returns its values plus the keys in file order
+func loadEnvOverrides(path string, strict bool) (map[string]string, []string, error) {
 	file, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	return parseEnvOverrides(file, path, strict)
+}
+
+// loadEnvSection is loadEnvOverrides for one section of a combined env file
+func loadEnvSection(path, section string, strict bool) (map[string]string, []string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
 	}
 	defer file.Close()
 
+	body, err := envwriter.ExtractSection(file, section)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return parseEnvOverrides(strings.NewReader(body), fmt.Sprintf("%s [%s]", path, section), strict)
+}
+
+// parseEnvOverrides parses dotenv or shell "export KEY=VALUE" lines. Malformed
+// lines are skipped, or reported as errors naming source and line when strict.
+func parseEnvOverrides(r io.Reader, source string, strict bool) (map[string]string, []string, error) {
 	overrides := make(map[string]string)
-	scanner := bufio.NewScanner(file)
+	var order []string
+	scanner := bufio.NewScanner(r)
 
-	for scanner.Scan() {
+	for lineNum := 1; scanner.Scan(); lineNum++ {
 		line := strings.TrimSpace(scanner.Text())
 
 		// Skip empty lines and comments
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
+		line = strings.TrimPrefix(line, "export ")
 
 		// Parse KEY=VALUE
 		parts := strings.SplitN(line, "=", 2)
 		if len(parts) != 2 {
+			if strict {
+				return nil, nil, fmt.Errorf("%s:%d: expected KEY=VALUE", source, lineNum)
+			}
 			continue
 		}
 
 		key := strings.TrimSpace(parts[0])
 		value := strings.TrimSpace(parts[1])
+		if strict && !overrideKeyRegex.MatchString(key) {
+			return nil, nil, fmt.Errorf("%s:%d: invalid key %q", source, lineNum, key)
+		}
 
 		// Remove quotes if present
 		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
@@ -304,10 +767,15 @@ func loadEnvOverrides(path string) (map[string]string, error) {
 			value = strings.ReplaceAll(value, "\\n", "\n")
 			value = strings.ReplaceAll(value, "\\r", "\r")
 			value = strings.ReplaceAll(value, "\\\\", "\\")
+		} else if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
 		}
 
+		if _, seen := overrides[key]; !seen {
+			order = append(order, key)
+		}
 		overrides[key] = value
 	}
 
-	return overrides, scanner.Err()
+	return overrides, order, scanner.Err()
 }
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"context"
+	"testing"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+	"github.com/JayDubyaEey/yeet/internal/provider/mock"
+)
+
+func TestResolveEnvVarsFetchesSharedSecretOnce(t *testing.T) {
+	cfg := &config.Config{
+		KeyVaultName: "kv",
+		Mappings: map[string]config.Mapping{
+			"DATABASE_URL":    {Type: config.ValueTypeKeyvault, Value: "db-url"},
+			"MIGRATIONS_URL":  {Type: config.ValueTypeKeyvault, Value: "db-url"},
+			"UNRELATED_VALUE": {Type: config.ValueTypeLiteral, Value: "x"},
+		},
+	}
+	prov := mock.New(map[string]string{"db-url": "postgres://db"})
+
+	envVars, err := resolveEnvVars(context.Background(), cfg, "kv", prov, config.EnvLocal)
+	if err != nil {
+		t.Fatalf("resolveEnvVars: %v", err)
+	}
+	for _, key := range []string{"DATABASE_URL", "MIGRATIONS_URL"} {
+		if got := envVars[key]; got != "postgres://db" {
+			t.Errorf("%s = %q, want %q", key, got, "postgres://db")
+		}
+	}
+
+	gets := 0
+	for _, call := range prov.Calls() {
+		if call.Method == "GetSecret" && call.Name == "db-url" {
+			gets++
+		}
+	}
+	if gets != 1 {
+		t.Errorf("GetSecret(db-url) called %d times, want 1", gets)
+	}
+}
@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+	"github.com/JayDubyaEey/yeet/internal/provider"
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+type setOptions struct {
+	fromFile string
+	stdin    bool
+	env      string
+}
+
+func newSetCmd() *cobra.Command {
+	opts := &setOptions{}
+	cmd := &cobra.Command{
+		Use:   "set",
+		Short: "Write KEY=VALUE pairs to their mapped Key Vault secrets",
+		Long: `Read KEY=VALUE pairs from a .env file or stdin, resolve each key to the
+Key Vault secret it is mapped to for the chosen environment, and set them all.
+
+Keys without a Key Vault mapping are skipped.`,
+		Example: `  yeet set --from-file .env
+  yeet set --from-file .env.docker --env docker
+  cat seed.env | yeet set --stdin --yes`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSet(cmd.Context(), opts, cmd.Flags().Changed("env"))
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.fromFile, "from-file", "", "Read KEY=VALUE pairs from this dotenv file")
+	cmd.Flags().BoolVar(&opts.stdin, "stdin", false, "Read KEY=VALUE pairs from standard input")
+	cmd.Flags().StringVarP(&opts.env, "env", "e", "local", "Environment whose mappings resolve keys to secret names (local or docker)")
+	_ = cmd.RegisterFlagCompletionFunc("env", completeEnvironments)
+	cmd.MarkFlagsMutuallyExclusive("from-file", "stdin")
+	cmd.MarkFlagsOneRequired("from-file", "stdin")
+
+	return cmd
+}
+
+// secretWrite is a single secret to be set, along with the keys that map to it
+type secretWrite struct {
+	name  string
+	value string
+	keys  []string
+}
+
+func runSet(ctx context.Context, opts *setOptions, envFlagSet bool) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	env, err := parseEnvironment(defaultEnvironment(cfg, opts.env, envFlagSet))
+	if err != nil {
+		return err
+	}
+
+	values, order, err := readSetInput(opts)
+	if err != nil {
+		return err
+	}
+	if len(order) == 0 {
+		ui.Warn("no KEY=VALUE pairs found")
+		return nil
+	}
+
+	writes, err := resolveSecretWrites(cfg, env, values, order)
+	if err != nil {
+		return err
+	}
+	if len(writes) == 0 {
+		ui.Warn("none of the keys map to a Key Vault secret for %s", env)
+		return nil
+	}
+
+	vault := cfg.KeyVaultName
+	if vaultOverride != "" {
+		vault = vaultOverride
+	}
+
+	prov := newProvider(cfg)
+	setter, ok := prov.(provider.Setter)
+	if !ok {
+		return fmt.Errorf("provider cannot set secrets")
+	}
+	if err := ensureLoggedIn(ctx, prov); err != nil {
+		return err
+	}
+
+	if err := confirmOrAbort(fmt.Sprintf("Set %d secret(s) in vault %s?", len(writes), vault)); err != nil {
+		return err
+	}
+
+	failures := applySecretWrites(ctx, setter, vault, writes)
+
+	ui.Blank()
+	if failures > 0 {
+		return fmt.Errorf("failed to set %d of %d secret(s)", failures, len(writes))
+	}
+	ui.Success("set %d secret(s) in %s", len(writes), vault)
+	return nil
+}
+
+// readSetInput parses the dotenv source selected by opts
+func readSetInput(opts *setOptions) (map[string]string, []string, error) {
+	if opts.stdin {
+		return parseEnvOverrides(os.Stdin, "stdin", true)
+	}
+	values, order, err := loadEnvOverrides(opts.fromFile, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", opts.fromFile, err)
+	}
+	return values, order, nil
+}
+
+// resolveSecretWrites maps each key to its Key Vault secret for env, skipping
+// keys that are unmapped or literal. Two keys sharing a secret must agree on its value.
+func resolveSecretWrites(cfg *config.Config, env config.Environment, values map[string]string, order []string) ([]*secretWrite, error) {
+	mappings := cfg.OutputMappings()
+	byName := make(map[string]*secretWrite)
+	var writes []*secretWrite
+
+	for _, key := range order {
+		mapping, ok := mappings[key]
+		if !ok {
+			ui.Warn("skipping %s: not mapped in %s", key, configSource())
+			continue
+		}
+		spec := cfg.ValueSpec(mapping, env)
+		if !spec.IsKeyvaultSecret() {
+			ui.Warn("skipping %s: not a Key Vault value for %s", key, env)
+			continue
+		}
+
+		if existing, ok := byName[spec.Value]; ok {
+			if existing.value != values[key] {
+				return nil, fmt.Errorf("keys %s and %s both map to secret %s but have different values", existing.keys[0], key, spec.Value)
+			}
+			existing.keys = append(existing.keys, key)
+			continue
+		}
+
+		w := &secretWrite{name: spec.Value, value: values[key], keys: []string{key}}
+		byName[spec.Value] = w
+		writes = append(writes, w)
+	}
+
+	sort.Slice(writes, func(i, j int) bool { return writes[i].name < writes[j].name })
+	return writes, nil
+}
+
+// applySecretWrites sets every secret with bounded concurrency, reports each
+// outcome, and returns the number of failures
+func applySecretWrites(ctx context.Context, setter provider.Setter, vault string, writes []*secretWrite) int {
+	errs := make([]error, len(writes))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, w := range writes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, w *secretWrite) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = setter.SetVaultSecret(ctx, vault, w.name, w.value)
+		}(i, w)
+	}
+	wg.Wait()
+
+	failures := 0
+	for i, w := range writes {
+		if errs[i] != nil {
+			failures++
+			ui.Item(ui.SymbolCross, "%s -> %s: %v", w.keys[0], w.name, errs[i])
+			continue
+		}
+		ui.Item(ui.SymbolCheck, "%s -> %s", w.keys[0], w.name)
+	}
+	return failures
+}
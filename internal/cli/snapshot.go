@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/JayDubyaEey/yeet/internal/envwriter"
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+// writeEnvSnapshot writes the environment the child will see to path: the
+// values yeet injects in an [injected] section and the rest of the inherited
+// environment in an [inherited] section. Every value is masked unless
+// showSecrets is set.
+func writeEnvSnapshot(path string, args []string, envVars map[string]string, showSecrets bool) error {
+	injected := make(map[string]string, len(envVars))
+	for key, value := range envVars {
+		injected[key] = snapshotValue(value, showSecrets)
+	}
+
+	inherited := make(map[string]string)
+	for _, entry := range parentEnv() {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || key == "" {
+			continue
+		}
+		if _, overridden := envVars[key]; !overridden {
+			inherited[key] = snapshotValue(value, showSecrets)
+		}
+	}
+
+	header := fmt.Sprintf("# Environment snapshot by yeet run\n# Command: %s\n# Generated: %s\n",
+		strings.Join(args, " "), time.Now().Format(time.RFC3339))
+	sections := []envwriter.Section{
+		{Name: "injected", Vars: injected},
+		{Name: "inherited", Vars: inherited},
+	}
+	if err := envwriter.WriteCombinedFile(path, sections, header, envwriter.DefaultWriteOptions()); err != nil {
+		return fmt.Errorf("failed to write env snapshot %s: %w", path, err)
+	}
+	ui.Info("wrote environment snapshot to %s (%d injected, %d inherited)", path, len(injected), len(inherited))
+	return nil
+}
+
+func snapshotValue(value string, showSecrets bool) string {
+	if showSecrets {
+		return value
+	}
+	return maskedValue
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+// loadSourceCmdEnv runs command through the shell and parses its stdout as
+// dotenv or "export KEY=VALUE" lines
+func loadSourceCmdEnv(ctx context.Context, command string) (map[string]string, []string, error) {
+	cmd := shellCommand(ctx, command)
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	cmd.Dir = workDir
+
+	if err := cmd.Run(); err != nil {
+		return nil, nil, fmt.Errorf("--source-cmd %q failed: %w", command, err)
+	}
+
+	return parseEnvOverrides(&stdout, "--source-cmd output", false)
+}
+
+// applySourceCmdEnv merges values printed by --source-cmd into envVars. By
+// default values from the vault win; with override set the command's win.
+func applySourceCmdEnv(ctx context.Context, envVars map[string]string, command string, override bool) error {
+	values, order, err := loadSourceCmdEnv(ctx, command)
+	if err != nil {
+		return err
+	}
+
+	applied := 0
+	for _, key := range order {
+		if _, exists := envVars[key]; exists && !override {
+			ui.Info("keeping vault value for %s over --source-cmd", key)
+			continue
+		}
+		envVars[key] = values[key]
+		applied++
+	}
+	ui.Success("loaded %d values from --source-cmd", applied)
+	return nil
+}
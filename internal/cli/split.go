@@ -0,0 +1,21 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+)
+
+// mappedSecretValue turns a value fetched from the vault into the value of
+// key: whitespace is trimmed, then the mapping's split picks its part
+func mappedSecretValue(mapping config.Mapping, key string, env config.Environment, value string) (string, error) {
+	value = trimSecretValue(mapping, key, env, value)
+	if mapping.Split == nil {
+		return value, nil
+	}
+	part, err := mapping.Split.Apply(value)
+	if err != nil {
+		return "", fmt.Errorf("split for %s (%s): %w", key, env, err)
+	}
+	return part, nil
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/JayDubyaEey/yeet/internal/provider"
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+func newStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Check the login and access to the configured vault",
+		Long: `Check separately that the provider is logged in and that the configured vault
+can be read, so "not logged in" and "logged in but no access to this vault"
+(a missing role assignment) are told apart before fetching anything.`,
+		Example: `  yeet status
+  yeet status --vault other-vault`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStatus(cmd.Context())
+		},
+	}
+}
+
+func runStatus(ctx context.Context) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	vault := cfg.KeyVaultName
+	if vaultOverride != "" {
+		vault = vaultOverride
+	}
+
+	// Checked even with --no-login-check, since that is what status is for
+	prov := newProvider(cfg)
+	if err := prov.EnsureLoggedIn(ctx); err != nil {
+		return notLoggedIn(prov, err)
+	}
+	ui.Success("logged in")
+
+	err = provider.CheckVaultAccess(ctx, prov, vault)
+	switch {
+	case errors.Is(err, provider.ErrUnsupported):
+		ui.Warn("vault %s: access not checked (%v)", vault, err)
+	case err != nil:
+		return fmt.Errorf("logged in, but cannot access vault %s: %w", vault, err)
+	default:
+		ui.Success("vault %s is accessible", vault)
+	}
+	return nil
+}
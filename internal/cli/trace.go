@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+// fetchTracer records how long each secret fetch took when --trace is set
+type fetchTracer struct {
+	mu      sync.Mutex
+	timings map[string]time.Duration
+}
+
+func newFetchTracer() *fetchTracer {
+	return &fetchTracer{timings: make(map[string]time.Duration)}
+}
+
+// record stores the duration of a single fetch; it is a no-op unless tracing is enabled
+func (t *fetchTracer) record(secretName string, d time.Duration) {
+	if !trace {
+		return
+	}
+	t.mu.Lock()
+	t.timings[secretName] = d
+	t.mu.Unlock()
+}
+
+// report prints recorded timings, slowest first
+func (t *fetchTracer) report() {
+	if !trace || len(t.timings) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(t.timings))
+	var total time.Duration
+	for name, d := range t.timings {
+		names = append(names, name)
+		total += d
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if t.timings[names[i]] != t.timings[names[j]] {
+			return t.timings[names[i]] > t.timings[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	ui.Trace("fetched %d secrets (sum %s, avg %s):", len(names), total.Round(time.Millisecond), (total / time.Duration(len(names))).Round(time.Millisecond))
+	for _, name := range names {
+		ui.Trace("  %8s  %s", t.timings[name].Round(time.Millisecond), name)
+	}
+}
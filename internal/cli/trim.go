@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/JayDubyaEey/yeet/internal/config"
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+var (
+	trimWhitespace string
+	defaultTrim    = config.TrimNone
+)
+
+// checkTrimFlag parses --trim-whitespace
+func checkTrimFlag() error {
+	mode, err := config.ParseTrimMode(trimWhitespace)
+	if err != nil {
+		return fmt.Errorf("invalid --trim-whitespace: %w", err)
+	}
+	defaultTrim = mode
+	return nil
+}
+
+// trimSecretValue applies the mapping's trim mode, or --trim-whitespace, to a
+// value fetched from the vault and reports under --verbose when it changed
+func trimSecretValue(mapping config.Mapping, key string, env config.Environment, value string) string {
+	trimmed := mapping.TrimModeOr(defaultTrim).Apply(value)
+	if trimmed != value {
+		ui.Info("trimmed whitespace from %s (%s)", key, env)
+	}
+	return trimmed
+}
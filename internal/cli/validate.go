@@ -2,43 +2,234 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/JayDubyaEey/yeet/internal/config"
-	"github.com/JayDubyaEey/yeet/internal/provider/azcli"
+	"github.com/JayDubyaEey/yeet/internal/envwriter"
+	"github.com/JayDubyaEey/yeet/internal/provider"
 	"github.com/JayDubyaEey/yeet/internal/ui"
 	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
 )
 
+type validateOptions struct {
+	againstFile string
+	env         string
+	extra       bool
+	timeout     time.Duration
+	raw         bool
+	crossEnv    bool
+	schema      bool
+	placeholder bool
+}
+
 func newValidateCmd() *cobra.Command {
+	opts := &validateOptions{}
 	cmd := &cobra.Command{
 		Use:   "validate",
 		Short: "Validate config and check secrets exist in Key Vault",
+		Example: `  yeet validate
+  yeet validate --against-file .env
+  yeet validate --against-file docker.env --env docker --extra
+  yeet validate --cross-env
+  yeet validate --schema`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runValidation(cmd.Context())
+			if opts.schema {
+				if err := runSchemaCheck(); err != nil {
+					return err
+				}
+			}
+			if opts.crossEnv {
+				if err := runCrossEnvCheck(); err != nil {
+					return err
+				}
+			}
+			if opts.againstFile != "" {
+				return runValidateAgainstFile(opts, cmd.Flags().Changed("env"))
+			}
+			return runValidation(cmd.Context(), opts)
 		},
 	}
+	cmd.Flags().StringVar(&opts.againstFile, "against-file", "", "Check offline that this env file defines every mapped key instead of checking the vault")
+	cmd.Flags().StringVarP(&opts.env, "env", "e", "local", "Environment whose mappings are required with --against-file (local or docker)")
+	_ = cmd.RegisterFlagCompletionFunc("env", completeEnvironments)
+	cmd.Flags().BoolVar(&opts.extra, "extra", false, "With --against-file, also report keys in the file that no mapping defines")
+	cmd.Flags().BoolVar(&opts.crossEnv, "cross-env", false, "First check offline that every mapping has a value in both local and docker")
+	cmd.Flags().BoolVar(&opts.schema, "schema", false, "First check offline that the config file only uses fields the config format defines, warning about unknown ones such as typos")
+	cmd.Flags().BoolVar(&opts.placeholder, "warn-on-placeholder", false, "Warn about literal values that look like unreplaced placeholders (your-..., CHANGEME, xxx, TODO)")
+	cmd.Flags().BoolVar(&opts.raw, "raw", false, "Report missing secrets as JSON on stdout")
+	cmd.Flags().DurationVar(&opts.timeout, "timeout", 0, "Stop and fail if validation takes longer than this (e.g. 2m; 0 means no limit)")
 	return cmd
 }
 
-func runValidation(ctx context.Context) error {
-	cfg, vault, prov, err := setupValidation()
+// runValidateAgainstFile checks that a dotenv file defines every key the
+// config maps for an environment, without contacting the vault
+func runValidateAgainstFile(opts *validateOptions, envFlagSet bool) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	env, err := parseEnvironment(defaultEnvironment(cfg, opts.env, envFlagSet))
+	if err != nil {
+		return err
+	}
+	if opts.placeholder {
+		warnPlaceholderLiterals(cfg)
+	}
+
+	if _, err := os.Stat(opts.againstFile); err != nil {
+		return fmt.Errorf("cannot read %s: %w", opts.againstFile, err)
+	}
+	fileVars, err := envwriter.ReadKeyValues(opts.againstFile)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", opts.againstFile, err)
+	}
+
+	outputMappings := cfg.OutputMappings()
+	var missing []string
+	for name, mapping := range outputMappings {
+		if cfg.ValueSpec(mapping, env) == nil {
+			continue
+		}
+		if _, ok := fileVars[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+
+	if opts.extra {
+		if extra := envwriter.UnmappedKeys(fileVars, outputMappings); len(extra) > 0 {
+			ui.Warn("%d keys in %s are not defined by any mapping:", len(extra), opts.againstFile)
+			for _, key := range extra {
+				ui.Warn("  - %s", key)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		ui.Error("%s is missing %d required keys for %s:", opts.againstFile, len(missing), env)
+		for _, key := range missing {
+			ui.Error("  - %s", key)
+		}
+		return fmt.Errorf("missing %d keys", len(missing))
+	}
+
+	ui.Success("validation passed: %s defines all keys for %s", opts.againstFile, env)
+	return nil
+}
+
+// runSchemaCheck checks the raw config JSON against the fields the config
+// format defines. Unknown fields are only warned about, since loading the
+// config ignores them, unless --strict-config is set.
+func runSchemaCheck() error {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", configSource(), err)
+	}
+	unknown, err := config.UnknownFields(data)
+	if err != nil {
+		return fmt.Errorf("invalid JSON in %s: %w", configSource(), err)
+	}
+	if len(unknown) == 0 {
+		ui.Success("%s only uses known fields", configSource())
+		return nil
+	}
+	return reportIgnoredFields(unknown)
+}
+
+// runCrossEnvCheck checks that every mapping resolves to a value in each
+// environment, so a key defined only for local is caught before docker runs
+func runCrossEnvCheck() error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	unresolved := 0
+	for _, env := range []config.Environment{config.EnvLocal, config.EnvDocker} {
+		var absent []string
+		for key, mapping := range cfg.Mappings {
+			if cfg.ValueSpec(mapping, env) == nil {
+				absent = append(absent, mapping.OutputName(key))
+			}
+		}
+		if len(absent) == 0 {
+			continue
+		}
+		sort.Strings(absent)
+		unresolved += len(absent)
+		ui.Error("%d keys are undefined in %s:", len(absent), env)
+		for _, key := range absent {
+			ui.Error("  - %s", key)
+		}
+	}
+	if unresolved > 0 {
+		return fmt.Errorf("%d keys do not resolve in every environment", unresolved)
+	}
+
+	ui.Success("every mapping resolves in local and docker")
+	return nil
+}
+
+func runValidation(ctx context.Context, opts *validateOptions) error {
+	if opts.raw {
+		// Keep stdout to the JSON report
+		ui.SetMuted(true)
+		defer ui.SetMuted(false)
+	}
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	cfg, vault, prov, err := setupValidation(ctx)
 	if err != nil {
 		return err
 	}
+	if opts.placeholder {
+		warnPlaceholderLiterals(cfg)
+	}
+
+	discovered, err := expandIncludes(ctx, prov, vault, cfg)
+	if err != nil {
+		return err
+	}
+	reportDiscoveredSecrets(discovered, cfg)
 
 	secretsToCheck := collectSecretsToValidate(cfg)
-	missing, err := checkSecretsExistence(ctx, prov, vault, secretsToCheck)
+	reportSharedSecrets(secretsToCheck, cfg)
+
+	missing, checked, err := checkSecretsExistence(ctx, prov, vault, secretsToCheck, concurrency)
 	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil {
+			return reportValidationTimeout(missing, checked, len(secretsToCheck), opts.timeout)
+		}
 		return err
 	}
 
-	return reportValidationResults(missing, vault)
+	violations, err := checkSecretRules(ctx, prov, vault, cfg)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		if len(missing) > 0 {
+			_ = reportValidationResults(missing, vault, opts.raw)
+		}
+		return reportRuleViolations(violations)
+	}
+
+	return reportValidationResults(missing, vault, opts.raw)
 }
 
-func setupValidation() (*config.Config, string, *azcli.Provider, error) {
-	cfg, err := config.Load(configPath)
+func setupValidation(ctx context.Context) (*config.Config, string, provider.Provider, error) {
+	cfg, err := loadConfig()
 	if err != nil {
 		return nil, "", nil, err
 	}
@@ -48,9 +239,9 @@ func setupValidation() (*config.Config, string, *azcli.Provider, error) {
 		vault = vaultOverride
 	}
 
-	prov := azcli.NewDefault()
-	if err := prov.EnsureLoggedIn(context.Background()); err != nil {
-		return nil, "", nil, fmt.Errorf("not logged in to Azure CLI: %w (run: yeet login)", err)
+	prov := newProvider(cfg)
+	if err := ensureLoggedIn(ctx, prov); err != nil {
+		return nil, "", nil, err
 	}
 
 	return cfg, vault, prov, nil
@@ -61,11 +252,11 @@ func collectSecretsToValidate(cfg *config.Config) map[string][]string {
 
 	for envKey, mapping := range cfg.Mappings {
 		// Check local environment
-		if localSpec := mapping.GetValueSpec(config.EnvLocal); localSpec != nil && localSpec.IsKeyvaultSecret() {
+		if localSpec := cfg.ValueSpec(mapping, config.EnvLocal); localSpec != nil && localSpec.IsKeyvaultSecret() {
 			secretsToCheck[localSpec.Value] = append(secretsToCheck[localSpec.Value], envKey+"(local)")
 		}
 		// Check docker environment
-		if dockerSpec := mapping.GetValueSpec(config.EnvDocker); dockerSpec != nil && dockerSpec.IsKeyvaultSecret() {
+		if dockerSpec := cfg.ValueSpec(mapping, config.EnvDocker); dockerSpec != nil && dockerSpec.IsKeyvaultSecret() {
 			secretsToCheck[dockerSpec.Value] = append(secretsToCheck[dockerSpec.Value], envKey+"(docker)")
 		}
 	}
@@ -73,32 +264,162 @@ func collectSecretsToValidate(cfg *config.Config) map[string][]string {
 	return secretsToCheck
 }
 
-func checkSecretsExistence(ctx context.Context, prov *azcli.Provider, vault string, secretsToCheck map[string][]string) ([]string, error) {
-	var missing []string
+func reportDiscoveredSecrets(discovered []string, cfg *config.Config) {
+	if len(cfg.Includes) == 0 {
+		return
+	}
+	ui.Success("includes matched %d secrets:", len(discovered))
+	for _, envKey := range discovered {
+		ui.Success("  - %s -> %s", envKey, cfg.Mappings[envKey].Value)
+	}
+}
 
+// reportSharedSecrets warns about secrets referenced by more than one env key,
+// which is usually deliberate aliasing but occasionally a copy-paste mistake.
+// Keys that split the secret into parts are meant to share it and not counted.
+func reportSharedSecrets(secretsToCheck map[string][]string, cfg *config.Config) {
+	shared := make(map[string][]string)
 	for secretName, envVars := range secretsToCheck {
-		exists, err := prov.SecretExists(ctx, vault, secretName)
-		if err != nil {
-			return nil, err
-		}
-		if !exists {
-			for _, envVar := range envVars {
-				missing = append(missing, fmt.Sprintf("%s -> %s", envVar, secretName))
+		var keys []string
+		for _, key := range distinctEnvKeys(envVars) {
+			if cfg.Mappings[key].Split == nil {
+				keys = append(keys, key)
 			}
 		}
+		if len(keys) > 1 {
+			shared[secretName] = keys
+		}
+	}
+	if len(shared) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(shared))
+	for secretName := range shared {
+		names = append(names, secretName)
+	}
+	sort.Strings(names)
+
+	ui.Warn("%d secrets are referenced by more than one key (confirm this is intended):", len(shared))
+	for _, secretName := range names {
+		ui.Warn("  - %s <- %s", secretName, strings.Join(shared[secretName], ", "))
 	}
+}
 
-	return missing, nil
+// distinctEnvKeys strips the "(env)" suffix from entries and returns the sorted unique keys
+func distinctEnvKeys(envVars []string) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, envVar := range envVars {
+		key := envVar
+		if i := strings.Index(key, "("); i >= 0 {
+			key = key[:i]
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
 }
 
-func reportValidationResults(missing []string, vault string) error {
+// checkSecretsExistence checks secrets concurrently and returns the missing
+// or disabled entries and how many secrets were checked, even when it stops early
+func checkSecretsExistence(ctx context.Context, prov provider.Provider, vault string, secretsToCheck map[string][]string, concurrency int) ([]missingValue, int, error) {
+	var (
+		mu      sync.Mutex
+		missing []missingValue
+		checked int
+	)
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for secretName, envVars := range secretsToCheck {
+		secretName, envVars := secretName, envVars
+		g.Go(func() error {
+			exists, disabled := true, false
+			secret, err := provider.GetWithMetadata(gctx, prov, vault, secretName)
+			switch {
+			case provider.IsNotFound(err):
+				exists = false
+			case provider.IsDisabled(err):
+				disabled = true
+			case err != nil:
+				return err
+			default:
+				disabled = !secret.Enabled
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			checked++
+			if !exists || disabled {
+				for _, envVar := range envVars {
+					key, env := parseEnvVarUse(envVar)
+					missing = append(missing, missingValue{Key: key, Environment: env, Secret: secretName, Disabled: disabled})
+				}
+			}
+			return nil
+		})
+	}
+	err := g.Wait()
+	if err != nil && ctx.Err() != nil {
+		// Report the deadline rather than whichever call it interrupted
+		err = ctx.Err()
+	}
+	return missing, checked, err
+}
+
+// reportValidationTimeout reports the partial result of a run cut short by --timeout
+func reportValidationTimeout(missing []missingValue, checked, total int, timeout time.Duration) error {
+	ui.Error("validation timed out after %s: checked %d of %d secrets", timeout, checked, total)
+	if len(missing) > 0 {
+		sortMissing(missing)
+		ui.Error("missing so far:")
+		for _, m := range missing {
+			ui.Error("  - %s", m)
+		}
+	}
+	return fmt.Errorf("validation timed out after %s", timeout)
+}
+
+func reportValidationResults(entries []missingValue, vault string, raw bool) error {
+	sortMissing(entries)
+	missing, disabled := splitDisabled(entries)
+	var err error
+	switch {
+	case len(missing) > 0 && len(disabled) > 0:
+		err = fmt.Errorf("missing %d secrets and %d disabled", len(missing), len(disabled))
+	case len(missing) > 0:
+		err = fmt.Errorf("missing %d secrets", len(missing))
+	case len(disabled) > 0:
+		err = fmt.Errorf("%d secrets are disabled", len(disabled))
+	}
+
+	if raw {
+		if entries == nil {
+			entries = []missingValue{}
+		}
+		if outErr := outputJSON(missingReport{Vault: vault, Missing: entries}); outErr != nil {
+			return outErr
+		}
+		return err
+	}
 	if len(missing) > 0 {
-		sort.Strings(missing)
 		ui.Error("missing %d secrets in vault %s:", len(missing), vault)
 		for _, m := range missing {
 			ui.Error("  - %s", m)
 		}
-		return fmt.Errorf("missing %d secrets", len(missing))
+	}
+	if len(disabled) > 0 {
+		ui.Error("%d secrets exist in vault %s but are disabled:", len(disabled), vault)
+		for _, m := range disabled {
+			ui.Error("  - %s", m)
+		}
+	}
+	if err != nil {
+		return err
 	}
 
 	ui.Success("validation passed: all secrets exist in %s", vault)
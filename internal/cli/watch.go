@@ -0,0 +1,237 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/JayDubyaEey/yeet/internal/ui"
+)
+
+const (
+	onChangeRestart = "restart"
+	onChangeSignal  = "signal"
+)
+
+var (
+	onSecretChange     string
+	secretPollInterval time.Duration
+	reloadSignal       string
+	stopGrace          time.Duration
+)
+
+// checkWatchFlags validates the --on-secret-change flags before anything is fetched
+func checkWatchFlags() error {
+	switch onSecretChange {
+	case "":
+		return nil
+	case onChangeRestart, onChangeSignal:
+	default:
+		return fmt.Errorf("invalid --on-secret-change %q: must be %q or %q", onSecretChange, onChangeRestart, onChangeSignal)
+	}
+	if bundlePath != "" {
+		return fmt.Errorf("--on-secret-change cannot be used with --vault-file")
+	}
+	if retryCount > 0 {
+		return fmt.Errorf("--on-secret-change cannot be combined with --retry")
+	}
+	if secretPollInterval <= 0 {
+		return fmt.Errorf("invalid --secret-poll-interval %s: must be positive", secretPollInterval)
+	}
+	if stopGrace < 0 {
+		return fmt.Errorf("invalid --stop-grace %s: must not be negative", stopGrace)
+	}
+	if onSecretChange == onChangeSignal {
+		if _, err := parseSignal(reloadSignal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watchedChild is a running child together with its exit result
+type watchedChild struct {
+	cmd  *exec.Cmd
+	done chan error
+}
+
+func startWatchedChild(args []string, envVars map[string]string, stdout, stderr io.Writer) (*watchedChild, error) {
+	ui.Info("running: %s %s", args[0], strings.Join(args[1:], " "))
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = parentEnv()
+	for key, value := range envVars {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+	}
+	cmd.Dir = workDir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// Its own process group, so a restart also stops anything it spawned.
+	// Stdin is only attached when the child can own the terminal.
+	tty, foreground := foregroundTTY()
+	if foreground {
+		cmd.Stdin = os.Stdin
+	}
+	setProcessGroup(cmd, tty, foreground)
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	child := &watchedChild{cmd: cmd, done: make(chan error, 1)}
+	go func() {
+		err := cmd.Wait()
+		if foreground {
+			reclaimTerminal(tty)
+		}
+		child.done <- err
+	}()
+	return child, nil
+}
+
+// refetchResult is the outcome of one background poll of the vault
+type refetchResult struct {
+	envVars map[string]string
+	err     error
+}
+
+// stop asks the child's process group to terminate and kills it after grace
+func (c *watchedChild) stop(grace time.Duration) {
+	if err := signalGroup(c.cmd, syscall.SIGTERM); err != nil {
+		killGroup(c.cmd)
+	}
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-c.done:
+		return
+	case <-timer.C:
+		ui.Warn("command did not exit within %s, killing it", grace)
+		killGroup(c.cmd)
+		<-c.done
+	}
+}
+
+// runWatched runs the child and polls the vault every --secret-poll-interval,
+// restarting or signalling the child when a resolved value changes
+func runWatched(ctx context.Context, args []string, envVars map[string]string, resolve func() (map[string]string, error), stdout, stderr io.Writer) error {
+	child, err := startWatchedChild(args, envVars, stdout, stderr)
+	if err != nil {
+		return handleCommandError(err)
+	}
+
+	// The child is in its own process group, so terminal signals must be forwarded
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(secretPollInterval)
+	defer ticker.Stop()
+
+	ui.Info("checking for secret changes every %s", secretPollInterval)
+
+	// refetch is non-nil while a poll is in flight; polls run in the
+	// background so signals and the child exiting are handled meanwhile
+	var refetch chan refetchResult
+
+	for {
+		select {
+		case err := <-child.done:
+			if err != nil {
+				return handleCommandError(err)
+			}
+			return nil
+
+		case sig := <-signals:
+			if err := signalGroup(child.cmd, sig); err != nil {
+				ui.Warn("could not forward %s to command: %v", sig, err)
+			}
+
+		case <-ctx.Done():
+			child.stop(stopGrace)
+			return fmt.Errorf("command stopped: %w", ctx.Err())
+
+		case <-ticker.C:
+			if refetch != nil {
+				continue // the previous poll is still running
+			}
+			refetch = make(chan refetchResult, 1)
+			go func(out chan<- refetchResult) {
+				// Polls are quiet; only a change or a failure is reported
+				ui.SetMuted(true)
+				newVars, err := resolve()
+				ui.SetMuted(false)
+				out <- refetchResult{envVars: newVars, err: err}
+			}(refetch)
+
+		case result := <-refetch:
+			refetch = nil
+			newVars, err := result.envVars, result.err
+			if err != nil {
+				if ctx.Err() != nil {
+					continue // reported by the ctx.Done case
+				}
+				ui.Warn("could not check for secret changes: %v (will retry)", err)
+				continue
+			}
+			changes := summarizeEnvChanges(envVars, newVars)
+			if changes == "" {
+				continue
+			}
+			envVars = newVars
+
+			if onSecretChange == onChangeSignal {
+				sig, _ := parseSignal(reloadSignal)
+				ui.Warn("secrets changed (%s), sending %s to command", changes, reloadSignal)
+				if err := signalGroup(child.cmd, sig); err != nil {
+					ui.Warn("could not signal command: %v", err)
+				}
+				continue
+			}
+
+			ui.Warn("secrets changed (%s), restarting command", changes)
+			child.stop(stopGrace)
+			if child, err = startWatchedChild(args, envVars, stdout, stderr); err != nil {
+				return handleCommandError(err)
+			}
+		}
+	}
+}
+
+// summarizeEnvChanges names the keys that differ between two resolutions,
+// without their values; it returns "" when nothing changed
+func summarizeEnvChanges(oldVars, newVars map[string]string) string {
+	var added, changed, removed []string
+	for key, value := range newVars {
+		old, ok := oldVars[key]
+		switch {
+		case !ok:
+			added = append(added, key)
+		case old != value:
+			changed = append(changed, key)
+		}
+	}
+	for key := range oldVars {
+		if _, ok := newVars[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+
+	var parts []string
+	for _, group := range []struct {
+		label string
+		keys  []string
+	}{{"changed", changed}, {"added", added}, {"removed", removed}} {
+		if len(group.keys) > 0 {
+			sort.Strings(group.keys)
+			parts = append(parts, group.label+": "+strings.Join(group.keys, ", "))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,79 @@
+//go:build !windows
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// reloadSignals are the signals --reload-signal accepts
+var reloadSignals = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"TERM": syscall.SIGTERM,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+}
+
+func parseSignal(name string) (os.Signal, error) {
+	sig, ok := reloadSignals[strings.TrimPrefix(strings.ToUpper(name), "SIG")]
+	if !ok {
+		return nil, fmt.Errorf("invalid --reload-signal %q: use HUP, INT, QUIT, TERM, USR1 or USR2", name)
+	}
+	return sig, nil
+}
+
+// foregroundTTY returns stdin's descriptor when it is a terminal and yeet's
+// process group is the terminal's foreground group
+func foregroundTTY() (int, bool) {
+	if !isTerminal(os.Stdin) {
+		return 0, false
+	}
+	tty := int(os.Stdin.Fd())
+	pgrp, err := unix.IoctlGetInt(tty, unix.TIOCGPGRP)
+	if err != nil || pgrp != syscall.Getpgrp() {
+		return 0, false
+	}
+	return tty, true
+}
+
+// setProcessGroup starts the child in its own process group. With a
+// foreground terminal that group takes over the terminal, since a background
+// group is stopped with SIGTTIN as soon as it reads from it.
+func setProcessGroup(cmd *exec.Cmd, tty int, foreground bool) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if foreground {
+		cmd.SysProcAttr.Foreground = true
+		cmd.SysProcAttr.Ctty = tty
+	}
+}
+
+// reclaimTerminal makes yeet's process group the terminal's foreground group
+// again once the child has exited
+func reclaimTerminal(tty int) {
+	// A background group changing the foreground group is sent SIGTTOU
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	_ = unix.IoctlSetPointerInt(tty, unix.TIOCSPGRP, syscall.Getpgrp())
+}
+
+// signalGroup sends sig to every process in the child's group
+func signalGroup(cmd *exec.Cmd, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return fmt.Errorf("unsupported signal %s", sig)
+	}
+	return syscall.Kill(-cmd.Process.Pid, s)
+}
+
+func killGroup(cmd *exec.Cmd) {
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,31 @@
+//go:build windows
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+func parseSignal(name string) (os.Signal, error) {
+	return nil, fmt.Errorf("--reload-signal is not supported on Windows; use --on-secret-change restart")
+}
+
+// Windows has no process groups or job control, so the child keeps the console
+func foregroundTTY() (int, bool) {
+	return 0, isTerminal(os.Stdin)
+}
+
+// Windows has no process groups to signal, so only the child itself is managed
+func setProcessGroup(cmd *exec.Cmd, tty int, foreground bool) {}
+
+func reclaimTerminal(tty int) {}
+
+func signalGroup(cmd *exec.Cmd, sig os.Signal) error {
+	return cmd.Process.Signal(sig)
+}
+
+func killGroup(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
+	"strings"
 )
 
 // ValueType represents the type of a configuration value
@@ -19,6 +21,20 @@ const (
 type ValueSpec struct {
 	Type  ValueType `json:"type"`
 	Value string    `json:"value"`
+	// Binary marks a base64-encoded value that is written to a file; the env
+	// var then holds the file path instead of the value
+	Binary bool `json:"binary,omitempty"`
+
+	// reference is the Key Vault reference Value was resolved from, if any
+	reference *referenceSource
+}
+
+// MarshalJSON writes a spec resolved from a Key Vault reference back as the
+// reference it was read from
+func (s ValueSpec) MarshalJSON() ([]byte, error) {
+	s.Type, s.Value = s.reference.restore(s.Type, s.Value)
+	type plainSpec ValueSpec // drops the method so this does not recurse
+	return json.Marshal(plainSpec(s))
 }
 
 // Mapping represents a single env var mapping with support for environments
@@ -28,8 +44,25 @@ type Mapping struct {
 	Docker *ValueSpec `json:"docker,omitempty"`
 
 	// Global fallback (when not environment-specific)
-	Type  ValueType `json:"type,omitempty"`
-	Value string    `json:"value,omitempty"`
+	Type   ValueType `json:"type,omitempty"`
+	Value  string    `json:"value,omitempty"`
+	Binary bool      `json:"binary,omitempty"`
+
+	// EnvName overrides the variable name emitted for this mapping, so the
+	// config key can differ from the name the application reads
+	EnvName string `json:"envName,omitempty"`
+
+	// Rules are checked against the resolved value before it is written
+	Rules *ValueRules `json:"validate,omitempty"`
+
+	// Trim strips whitespace from fetched Key Vault values (none, trailing or all)
+	Trim TrimMode `json:"trim,omitempty"`
+
+	// Split takes one part of a composite Key Vault value
+	Split *SplitSpec `json:"split,omitempty"`
+
+	// reference is the Key Vault reference the global value was resolved from, if any
+	reference *referenceSource
 }
 
 // Environment represents the target environment
@@ -42,32 +75,59 @@ const (
 
 // Config represents the env.config.json structure
 type Config struct {
-	KeyVaultName string             `json:"keyVaultName"`
-	Mappings     map[string]Mapping `json:"mappings"`
+	// Schema is the optional "$schema" URL editors use for completion and
+	// validation; yeet only carries it through load and save
+	Schema string `json:"$schema,omitempty"`
+	// Provider selects the secret backend: ProviderAzure (default) or ProviderAWS
+	Provider string `json:"provider,omitempty"`
+	// KeyVaultName is the Azure Key Vault name; with ProviderAWS it is an
+	// optional prefix put in front of every secret name
+	KeyVaultName string `json:"keyVaultName"`
+	// AWSRegion is the AWS Secrets Manager region (default from AWS_REGION or
+	// the AWS CLI profile)
+	AWSRegion string `json:"awsRegion,omitempty"`
+	// NamePattern overrides the regex env var names must match (default DefaultNamePattern)
+	NamePattern string `json:"namePattern,omitempty"`
+	// SecretNamePattern, if set, is a regex every referenced Key Vault secret
+	// name must match (e.g. an organisation's <team>-<app>-<purpose> policy)
+	SecretNamePattern string `json:"secretNamePattern,omitempty"`
+	// VaultDNSSuffix addresses the vault as https://NAME.<suffix> for clouds
+	// with custom Key Vault DNS (default DefaultVaultDNSSuffix)
+	VaultDNSSuffix string `json:"vaultDnsSuffix,omitempty"`
+	// DefaultEnvironment is used by commands taking --env when the flag is not given
+	DefaultEnvironment Environment `json:"defaultEnvironment,omitempty"`
+	Includes           []Include   `json:"includes,omitempty"`
+	// ResolveOrder overrides, per environment, which of a mapping's specs are
+	// tried and in what order (e.g. docker: ["docker", "local", "global"])
+	ResolveOrder map[Environment][]string `json:"resolveOrder,omitempty"`
+	Mappings     map[string]Mapping       `json:"mappings"`
+
+	// Ignored lists fields in the parsed JSON that the config format does not
+	// define (see UnknownFields); they are not kept when the config is saved
+	Ignored []string `json:"-"`
 }
 
-// GetValueSpec returns the appropriate ValueSpec for the given environment
+// GetValueSpec returns the appropriate ValueSpec for the given environment:
+// the environment-specific spec, falling back to the global value
 func (m *Mapping) GetValueSpec(env Environment) *ValueSpec {
-	switch env {
-	case EnvLocal:
-		if m.Local != nil {
-			return m.Local
-		}
-	case EnvDocker:
-		if m.Docker != nil {
-			return m.Docker
-		}
-	}
+	return m.ResolveValueSpec(defaultResolveOrder(env))
+}
 
-	// Fallback to global value if present
-	if m.Type != "" && m.Value != "" {
-		return &ValueSpec{
-			Type:  m.Type,
-			Value: m.Value,
-		}
+// OutputName returns the variable name emitted for the mapping stored under key
+func (m *Mapping) OutputName(key string) string {
+	if m.EnvName != "" {
+		return m.EnvName
 	}
+	return key
+}
 
-	return nil
+// OutputMappings returns the mappings keyed by the variable name they emit
+func (c *Config) OutputMappings() map[string]Mapping {
+	out := make(map[string]Mapping, len(c.Mappings))
+	for key, mapping := range c.Mappings {
+		out[mapping.OutputName(key)] = mapping
+	}
+	return out
 }
 
 // IsKeyvaultSecret returns true if the value should be fetched from Key Vault
@@ -82,11 +142,29 @@ func (v *ValueSpec) IsLiteral() bool {
 
 // rawMapping helps parse JSON where value can be string or object
 type rawMapping struct {
-	KeyVaultName string                     `json:"keyVaultName"`
-	Mappings     map[string]json.RawMessage `json:"mappings"`
+	Schema            string                     `json:"$schema"`
+	Provider          string                     `json:"provider"`
+	KeyVaultName      string                     `json:"keyVaultName"`
+	AWSRegion         string                     `json:"awsRegion"`
+	NamePattern       string                     `json:"namePattern"`
+	SecretNamePattern string                     `json:"secretNamePattern"`
+	VaultDNSSuffix    string                     `json:"vaultDnsSuffix"`
+	Includes          []Include                  `json:"includes"`
+	DefaultEnv        Environment                `json:"defaultEnvironment"`
+	ResolveOrder      map[Environment][]string   `json:"resolveOrder"`
+	Mappings          map[string]json.RawMessage `json:"mappings"`
 }
 
-var envVarRegex = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+// Secret backends a config can select with "provider"
+const (
+	ProviderAzure = "azure"
+	ProviderAWS   = "aws"
+)
+
+// DefaultNamePattern is the env var name pattern used when the config does not set one
+const DefaultNamePattern = `^[A-Z_][A-Z0-9_]*$`
+
+var envVarRegex = regexp.MustCompile(DefaultNamePattern)
 
 // Load reads and validates env.config.json
 func Load(path string) (*Config, error) {
@@ -100,7 +178,39 @@ func Load(path string) (*Config, error) {
 		return nil, err
 	}
 
-	if err := validateConfig(cfg); err != nil {
+	if err := Validate(cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// LoadLenient reads a config without failing on validation errors, for tools
+// that work with configs still being edited. Problems in the returned config
+// are listed instead; an error is returned only if the file cannot be read or
+// parsed at all.
+func LoadLenient(path string) (*Config, []error, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	cfg, err := parseConfig(data, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, Problems(cfg), nil
+}
+
+// Parse parses and validates config JSON that was not read from a file
+func Parse(data []byte) (*Config, error) {
+	cfg, err := parseConfig(data, "config data")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Validate(cfg); err != nil {
 		return nil, err
 	}
 
@@ -115,9 +225,20 @@ func parseConfig(data []byte, path string) (*Config, error) {
 	}
 
 	cfg := &Config{
-		KeyVaultName: raw.KeyVaultName,
-		Mappings:     make(map[string]Mapping),
+		Schema:             raw.Schema,
+		Provider:           raw.Provider,
+		KeyVaultName:       raw.KeyVaultName,
+		AWSRegion:          raw.AWSRegion,
+		NamePattern:        raw.NamePattern,
+		SecretNamePattern:  raw.SecretNamePattern,
+		VaultDNSSuffix:     raw.VaultDNSSuffix,
+		Includes:           raw.Includes,
+		DefaultEnvironment: raw.DefaultEnv,
+		ResolveOrder:       raw.ResolveOrder,
+		Mappings:           make(map[string]Mapping),
 	}
+	// data is known to be valid JSON here, so this cannot fail
+	cfg.Ignored, _ = UnknownFields(data)
 
 	for key, rawVal := range raw.Mappings {
 		mapping, err := parseMapping(key, rawVal)
@@ -127,6 +248,10 @@ func parseConfig(data []byte, path string) (*Config, error) {
 		cfg.Mappings[key] = mapping
 	}
 
+	if err := resolveKeyVaultReferences(cfg); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
 
@@ -150,38 +275,214 @@ func parseMapping(key string, rawVal json.RawMessage) (Mapping, error) {
 	return mapping, nil
 }
 
-// validateConfig validates the configuration
-func validateConfig(cfg *Config) error {
-	if cfg.KeyVaultName == "" {
-		return fmt.Errorf("keyVaultName is required")
+// MarshalJSON writes a mapping in canonical form: the "secret-name" shorthand
+// when it is nothing but a global Key Vault reference, the typed object
+// otherwise, so loading and saving a config always produces the same file
+func (m Mapping) MarshalJSON() ([]byte, error) {
+	m.Type, m.Value = m.reference.restore(m.Type, m.Value)
+	if m.isShorthand() {
+		return json.Marshal(m.Value)
 	}
-	if len(cfg.Mappings) == 0 {
-		return fmt.Errorf("at least one mapping is required")
+	type typedMapping Mapping // drops the method so this does not recurse
+	return json.Marshal(typedMapping(m))
+}
+
+// isShorthand reports whether parseMapping would read the mapping back from
+// a plain string
+func (m Mapping) isShorthand() bool {
+	return m.Type == ValueTypeKeyvault && m.Value != "" &&
+		m.Local == nil && m.Docker == nil && !m.Binary &&
+		m.EnvName == "" && m.Rules == nil && m.Trim == "" && m.Split == nil
+}
+
+// Validate checks that a configuration is complete and well-formed
+func Validate(cfg *Config) error {
+	if problems := Problems(cfg); len(problems) > 0 {
+		return problems[0]
 	}
+	return nil
+}
+
+// Problems returns every validation error in cfg, in a stable order, rather
+// than stopping at the first like Validate
+func Problems(cfg *Config) []error {
+	if cfg == nil {
+		return []error{fmt.Errorf("config cannot be nil")}
+	}
+
+	var problems []error
+	switch cfg.Provider {
+	case "", ProviderAzure:
+		if cfg.KeyVaultName == "" {
+			problems = append(problems, fmt.Errorf("keyVaultName is required"))
+		}
+	case ProviderAWS:
+		// keyVaultName is an optional secret name prefix
+	default:
+		problems = append(problems, fmt.Errorf("invalid provider %q: must be %q or %q", cfg.Provider, ProviderAzure, ProviderAWS))
+	}
+	if cfg.AWSRegion != "" && cfg.Provider != ProviderAWS {
+		problems = append(problems, fmt.Errorf("awsRegion is only used with provider %q", ProviderAWS))
+	}
+	if len(cfg.Mappings) == 0 && len(cfg.Includes) == 0 {
+		problems = append(problems, fmt.Errorf("at least one mapping or include is required"))
+	}
+	if cfg.DefaultEnvironment != "" && cfg.DefaultEnvironment != EnvLocal && cfg.DefaultEnvironment != EnvDocker {
+		problems = append(problems, fmt.Errorf("invalid defaultEnvironment %q: must be %q or %q", cfg.DefaultEnvironment, EnvLocal, EnvDocker))
+	}
+	if err := validateResolveOrder(cfg.ResolveOrder); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.VaultDNSSuffix != "" {
+		if err := ValidateDNSSuffix(cfg.VaultDNSSuffix); err != nil {
+			problems = append(problems, fmt.Errorf("vaultDnsSuffix: %w", err))
+		}
+	}
+	for i, inc := range cfg.Includes {
+		if err := validateInclude(inc); err != nil {
+			problems = append(problems, fmt.Errorf("includes[%d]: %w", i, err))
+		}
+	}
+	nameRegex, err := cfg.NameRegexp()
+	if err != nil {
+		// Keep checking the mappings against the default pattern
+		problems = append(problems, err)
+		nameRegex = envVarRegex
+	}
+
+	keys := make([]string, 0, len(cfg.Mappings))
+	for key := range cfg.Mappings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := validateMapping(key, cfg.Mappings[key], nameRegex); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if err := validateOutputNames(cfg); err != nil {
+		problems = append(problems, err)
+	}
+	if err := validateSecretNames(cfg); err != nil {
+		problems = append(problems, err)
+	}
+	return problems
+}
+
+// validateSecretNames checks every Key Vault secret the mappings reference
+// against secretNamePattern, listing all offending names at once
+func validateSecretNames(cfg *Config) error {
+	if cfg.SecretNamePattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(cfg.SecretNamePattern)
+	if err != nil {
+		return fmt.Errorf("invalid secretNamePattern %q: %w", cfg.SecretNamePattern, err)
+	}
+
+	seen := make(map[string]bool)
+	var bad []string
+	for _, mapping := range cfg.Mappings {
+		specs := []*ValueSpec{mapping.Local, mapping.Docker}
+		if mapping.Type != "" && mapping.Value != "" {
+			specs = append(specs, &ValueSpec{Type: mapping.Type, Value: mapping.Value})
+		}
+		for _, spec := range specs {
+			if !spec.IsKeyvaultSecret() || seen[spec.Value] {
+				continue
+			}
+			seen[spec.Value] = true
+			if !re.MatchString(spec.Value) {
+				bad = append(bad, spec.Value)
+			}
+		}
+	}
+	if len(bad) == 0 {
+		return nil
+	}
+	sort.Strings(bad)
+	return fmt.Errorf("%d secret names do not match secretNamePattern %s: %s", len(bad), cfg.SecretNamePattern, strings.Join(bad, ", "))
+}
+
+// validateOutputNames rejects mappings that would emit the same variable name
+func validateOutputNames(cfg *Config) error {
+	emitted := make(map[string]string, len(cfg.Mappings))
 	for key, mapping := range cfg.Mappings {
-		if err := validateMapping(key, mapping); err != nil {
-			return err
+		name := mapping.OutputName(key)
+		if other, ok := emitted[name]; ok {
+			a, b := other, key
+			if b < a {
+				a, b = b, a
+			}
+			return fmt.Errorf("mappings %s and %s both emit %s", a, b, name)
 		}
+		emitted[name] = key
 	}
 	return nil
 }
 
+// DefaultVaultDNSSuffix is the Key Vault DNS suffix of the public Azure cloud
+const DefaultVaultDNSSuffix = "vault.azure.net"
+
+var dnsSuffixRegex = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9-]*[a-z0-9]$`)
+
+// ValidateDNSSuffix checks suffix is a bare lower-case domain such as
+// vault.azure.net, with no scheme, port or path
+func ValidateDNSSuffix(suffix string) error {
+	if !dnsSuffixRegex.MatchString(suffix) {
+		return fmt.Errorf("invalid DNS suffix %q: must be a domain such as %s, without https:// or a path", suffix, DefaultVaultDNSSuffix)
+	}
+	return nil
+}
+
+// NameRegexp returns the compiled env var name pattern for this config
+func (c *Config) NameRegexp() (*regexp.Regexp, error) {
+	if c.NamePattern == "" {
+		return envVarRegex, nil
+	}
+	re, err := regexp.Compile(c.NamePattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid namePattern %q: %w", c.NamePattern, err)
+	}
+	return re, nil
+}
+
 // validateMapping validates a single mapping
-func validateMapping(key string, mapping Mapping) error {
-	if err := validateEnvironmentVarName(key); err != nil {
+func validateMapping(key string, mapping Mapping, nameRegex *regexp.Regexp) error {
+	if err := validateEnvironmentVarName(key, nameRegex); err != nil {
 		return err
 	}
 
+	if mapping.EnvName != "" {
+		if err := validateEnvironmentVarName(mapping.EnvName, nameRegex); err != nil {
+			return fmt.Errorf("envName for %s: %w", key, err)
+		}
+	}
+
 	if err := validateMappingHasValues(key, mapping); err != nil {
 		return err
 	}
 
+	if err := validateRules(key, mapping.Rules); err != nil {
+		return err
+	}
+
+	if _, err := ParseTrimMode(string(mapping.Trim)); err != nil {
+		return fmt.Errorf("trim for %s: %w", key, err)
+	}
+
+	if mapping.Split != nil {
+		if err := mapping.Split.validate(); err != nil {
+			return fmt.Errorf("split for %s: %w", key, err)
+		}
+	}
+
 	return validateIndividualSpecs(key, mapping)
 }
 
-func validateEnvironmentVarName(key string) error {
-	if !envVarRegex.MatchString(key) {
-		return fmt.Errorf("invalid env var name: %s (must match ^[A-Z_][A-Z0-9_]*$)", key)
+func validateEnvironmentVarName(key string, nameRegex *regexp.Regexp) error {
+	if !nameRegex.MatchString(key) {
+		return fmt.Errorf("invalid env var name: %s (must match %s)", key, nameRegex.String())
 	}
 	return nil
 }
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// UnknownFields returns the paths of fields in config JSON that the config
+// format does not define, such as "mappings.API_KEY.vaule". encoding/json
+// drops them silently, so a typo would otherwise only show up as a missing
+// value later.
+func UnknownFields(data []byte) ([]string, error) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var unknown []string
+	collectUnknownFields(doc, reflect.TypeOf(Config{}), "", &unknown)
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+// collectUnknownFields walks a decoded JSON value alongside the Go type it is
+// unmarshalled into. Values whose shape does not match t (such as a mapping
+// written as a plain secret name) are left for encoding/json to report.
+func collectUnknownFields(value any, t reflect.Type, path string, unknown *[]string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := jsonFields(t)
+			for name, child := range v {
+				// encoding/json matches names case-insensitively
+				field, ok := fields[strings.ToLower(name)]
+				if !ok {
+					*unknown = append(*unknown, joinFieldPath(path, name))
+					continue
+				}
+				collectUnknownFields(child, field, joinFieldPath(path, name), unknown)
+			}
+		case reflect.Map:
+			for key, child := range v {
+				collectUnknownFields(child, t.Elem(), joinFieldPath(path, key), unknown)
+			}
+		}
+	case []any:
+		if t.Kind() == reflect.Slice {
+			for _, child := range v {
+				collectUnknownFields(child, t.Elem(), path+"[]", unknown)
+			}
+		}
+	}
+}
+
+// jsonFields returns the lowercased JSON names of t's fields and their types
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = f.Type
+	}
+	return fields
+}
+
+func joinFieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnknownFields(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "schema key",
+			data: `{"$schema": "https://example.com/yeet.json", "keyVaultName": "kv", "mappings": {}}`,
+		},
+		{
+			name: "typo in mapping",
+			data: `{"keyVaultName": "kv", "mappings": {"A": {"type": "literal", "vaule": "x"}}}`,
+			want: []string{"mappings.A.vaule"},
+		},
+		{
+			name: "schema key below the top level",
+			data: `{"keyVaultName": "kv", "mappings": {"A": {"$schema": "x", "value": "a"}}}`,
+			want: []string{"mappings.A.$schema"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnknownFields([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("UnknownFields: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
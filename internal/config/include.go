@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KeyTransform controls how a discovered secret name becomes an env var name
+type KeyTransform string
+
+const (
+	KeyTransformUpperSnake KeyTransform = "upper-snake"
+	KeyTransformNone       KeyTransform = "none"
+)
+
+// Include pulls every vault secret whose name starts with IncludePrefix into
+// the config as a global keyvault mapping, without listing each one
+type Include struct {
+	IncludePrefix string       `json:"includePrefix"`
+	KeyTransform  KeyTransform `json:"keyTransform,omitempty"`
+	StripPrefix   bool         `json:"stripPrefix,omitempty"`
+}
+
+// Matches reports whether a vault secret name is covered by this include
+func (i Include) Matches(secretName string) bool {
+	return strings.HasPrefix(secretName, i.IncludePrefix)
+}
+
+// EnvKey returns the env var name for a matching secret name
+func (i Include) EnvKey(secretName string) string {
+	name := secretName
+	if i.StripPrefix {
+		name = strings.TrimPrefix(name, i.IncludePrefix)
+	}
+	if i.KeyTransform == KeyTransformNone {
+		return name
+	}
+	return ToShoutingSnakeCase(name)
+}
+
+func validateInclude(inc Include) error {
+	if inc.IncludePrefix == "" {
+		return fmt.Errorf("includePrefix is required")
+	}
+	switch inc.KeyTransform {
+	case "", KeyTransformUpperSnake, KeyTransformNone:
+		return nil
+	default:
+		return fmt.Errorf("invalid keyTransform %q: must be %q or %q", inc.KeyTransform, KeyTransformUpperSnake, KeyTransformNone)
+	}
+}
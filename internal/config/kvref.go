@@ -0,0 +1,174 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	kvRefPrefix = "@Microsoft.KeyVault("
+	kvRefSuffix = ")"
+)
+
+// KeyVaultReference is a parsed App Service "@Microsoft.KeyVault(...)" reference
+type KeyVaultReference struct {
+	Vault   string
+	Secret  string
+	Version string
+}
+
+// IsKeyVaultReference reports whether value uses the App Service reference syntax
+func IsKeyVaultReference(value string) bool {
+	value = strings.TrimSpace(value)
+	return strings.HasPrefix(value, kvRefPrefix) && strings.HasSuffix(value, kvRefSuffix)
+}
+
+// ParseKeyVaultReference parses either reference form:
+//
+//	@Microsoft.KeyVault(SecretUri=https://VAULT.vault.azure.net/secrets/NAME/VERSION)
+//	@Microsoft.KeyVault(VaultName=VAULT;SecretName=NAME;SecretVersion=VERSION)
+//
+// The version is optional in both.
+func ParseKeyVaultReference(value string) (*KeyVaultReference, error) {
+	if !IsKeyVaultReference(value) {
+		return nil, fmt.Errorf("not a Key Vault reference: %s", value)
+	}
+	value = strings.TrimSpace(value)
+	body := value[len(kvRefPrefix) : len(value)-len(kvRefSuffix)]
+
+	params := make(map[string]string)
+	for _, part := range strings.Split(body, ";") {
+		name, val, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid Key Vault reference parameter %q", part)
+		}
+		params[strings.ToLower(name)] = strings.TrimSpace(val)
+	}
+
+	if uri, ok := params["secreturi"]; ok {
+		return parseSecretURI(uri)
+	}
+
+	ref := &KeyVaultReference{
+		Vault:   params["vaultname"],
+		Secret:  params["secretname"],
+		Version: params["secretversion"],
+	}
+	if ref.Vault == "" || ref.Secret == "" {
+		return nil, fmt.Errorf("Key Vault reference needs SecretUri or both VaultName and SecretName")
+	}
+	return ref, nil
+}
+
+// parseSecretURI splits https://VAULT.vault.azure.net/secrets/NAME[/VERSION]
+func parseSecretURI(uri string) (*KeyVaultReference, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid SecretUri %q", uri)
+	}
+	vault, _, ok := strings.Cut(u.Hostname(), ".")
+	if !ok || vault == "" {
+		return nil, fmt.Errorf("invalid SecretUri %q: cannot determine vault", uri)
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 2 || len(segments) > 3 || segments[0] != "secrets" || segments[1] == "" {
+		return nil, fmt.Errorf("invalid SecretUri %q: expected /secrets/NAME[/VERSION]", uri)
+	}
+
+	ref := &KeyVaultReference{Vault: vault, Secret: segments[1]}
+	if len(segments) == 3 {
+		ref.Version = segments[2]
+	}
+	return ref, nil
+}
+
+// referenceSource remembers a Key Vault reference as it was written, so
+// saving the config writes it back instead of the secret it resolved to
+type referenceSource struct {
+	Type   ValueType
+	Value  string
+	Vault  string
+	Secret string
+}
+
+// restore returns the type/value to save: the original reference while the
+// spec still resolves to its secret, and typ/value unchanged otherwise
+func (r *referenceSource) restore(typ ValueType, value string) (ValueType, string) {
+	if r == nil || typ != ValueTypeKeyvault || value != r.Secret {
+		return typ, value
+	}
+	return r.Type, r.Value
+}
+
+// resolveKeyVaultReferences turns literal values written as Key Vault
+// references into keyvault specs for the config's own vault
+func resolveKeyVaultReferences(cfg *Config) error {
+	for key, mapping := range cfg.Mappings {
+		var err error
+		if mapping.reference, err = resolveReference(key, "global", &mapping.Type, &mapping.Value, cfg.KeyVaultName); err != nil {
+			return err
+		}
+		if mapping.Local != nil {
+			if mapping.Local.reference, err = resolveReference(key, "local", &mapping.Local.Type, &mapping.Local.Value, cfg.KeyVaultName); err != nil {
+				return err
+			}
+		}
+		if mapping.Docker != nil {
+			if mapping.Docker.reference, err = resolveReference(key, "docker", &mapping.Docker.Type, &mapping.Docker.Value, cfg.KeyVaultName); err != nil {
+				return err
+			}
+		}
+		cfg.Mappings[key] = mapping
+	}
+	return nil
+}
+
+// resolveReference rewrites one type/value pair in place if value is a
+// reference, and returns what was written so it can be saved back
+func resolveReference(key, context string, typ *ValueType, value *string, vault string) (*referenceSource, error) {
+	if !IsKeyVaultReference(*value) {
+		return nil, nil
+	}
+	ref, err := ParseKeyVaultReference(*value)
+	if err != nil {
+		return nil, fmt.Errorf("%s (%s): %w", key, context, err)
+	}
+	if !strings.EqualFold(ref.Vault, vault) {
+		return nil, fmt.Errorf("%s (%s): references vault %s but keyVaultName is %s", key, context, ref.Vault, vault)
+	}
+	if ref.Version != "" {
+		return nil, fmt.Errorf("%s (%s): pinned secret versions are not supported; drop the version to use the latest", key, context)
+	}
+	source := &referenceSource{Type: *typ, Value: *value, Vault: ref.Vault, Secret: ref.Secret}
+	*typ = ValueTypeKeyvault
+	*value = ref.Secret
+	return source, nil
+}
+
+// forgetReferencesOutside returns mappings with the references to vaults other
+// than vault dropped, so a config whose keyVaultName changed saves the plain
+// secret names instead of references it would then fail to load
+func forgetReferencesOutside(mappings map[string]Mapping, vault string) map[string]Mapping {
+	stale := func(r *referenceSource) bool {
+		return r != nil && !strings.EqualFold(r.Vault, vault)
+	}
+	out := make(map[string]Mapping, len(mappings))
+	for key, mapping := range mappings {
+		if stale(mapping.reference) {
+			mapping.reference = nil
+		}
+		if mapping.Local != nil && stale(mapping.Local.reference) {
+			local := *mapping.Local
+			local.reference = nil
+			mapping.Local = &local
+		}
+		if mapping.Docker != nil && stale(mapping.Docker.reference) {
+			docker := *mapping.Docker
+			docker.reference = nil
+			mapping.Docker = &docker
+		}
+		out[key] = mapping
+	}
+	return out
+}
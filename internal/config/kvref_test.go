@@ -0,0 +1,59 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const referenceConfig = `{
+  "keyVaultName": "my-kv",
+  "mappings": {
+    "A": "@Microsoft.KeyVault(VaultName=my-kv;SecretName=a-secret)",
+    "B": {
+      "local": {
+        "type": "literal",
+        "value": "@Microsoft.KeyVault(SecretUri=https://my-kv.vault.azure.net/secrets/b-secret/)"
+      },
+      "docker": {
+        "type": "literal",
+        "value": "plain"
+      }
+    },
+    "C": {
+      "type": "literal",
+      "value": "@Microsoft.KeyVault(VaultName=my-kv;SecretName=c-secret)",
+      "envName": "CC"
+    }
+  }
+}
+`
+
+func TestSaveKeepsKeyVaultReferences(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "env.config.json")
+	if err := os.WriteFile(path, []byte(referenceConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for key, want := range map[string]string{"A": "a-secret", "B": "b-secret", "C": "c-secret"} {
+		spec := cfg.ValueSpec(cfg.Mappings[key], EnvLocal)
+		if spec == nil || !spec.IsKeyvaultSecret() || spec.Value != want {
+			t.Errorf("%s resolves to %+v, want Key Vault secret %s", key, spec, want)
+		}
+	}
+
+	if err := Save(cfg, path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != referenceConfig {
+		t.Errorf("load+save changed the config:\n%s", data)
+	}
+}
@@ -0,0 +1,13 @@
+package config
+
+import "regexp"
+
+// placeholderRegex matches template values that were meant to be replaced:
+// your-api-key, CHANGEME, replace_me, xxx, TODO and <token>
+var placeholderRegex = regexp.MustCompile(`(?i)^your[-_]|change[-_]?me|replace[-_]?me|xxx|\btodo\b|^<[^>]+>$`)
+
+// LooksLikePlaceholder reports whether value looks like an unreplaced
+// template value rather than a real setting
+func LooksLikePlaceholder(value string) bool {
+	return placeholderRegex.MatchString(value)
+}
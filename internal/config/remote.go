@@ -0,0 +1,73 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxRemoteConfigSize bounds how much of a remote config response is read
+const maxRemoteConfigSize = 1 << 20
+
+// remoteClient fetches remote configs. Unlike http.DefaultClient it never
+// follows a redirect off https, which would send the config, and possibly
+// the Authorization header, in the clear.
+var remoteClient = &http.Client{
+	Timeout: 15 * time.Second,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme != "https" {
+			return fmt.Errorf("refusing redirect to non-https URL %s", req.URL.Redacted())
+		}
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after 10 redirects")
+		}
+		return nil
+	},
+}
+
+// IsRemote reports whether path is a URL rather than a file path
+func IsRemote(path string) bool {
+	return strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://")
+}
+
+// FetchRemote downloads a config over HTTPS. authorization, if set, is sent
+// as the Authorization header.
+func FetchRemote(ctx context.Context, url, authorization string, timeout time.Duration) ([]byte, error) {
+	if !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("remote config %s must use https", url)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config URL %s: %w", url, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := remoteClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch config from %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch config from %s: %s", url, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteConfigSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config from %s: %w", url, err)
+	}
+	if len(data) > maxRemoteConfigSize {
+		return nil, fmt.Errorf("config from %s is larger than %d bytes", url, maxRemoteConfigSize)
+	}
+	return data, nil
+}
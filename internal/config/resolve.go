@@ -0,0 +1,89 @@
+package config
+
+import "fmt"
+
+// ResolveGlobal names the mapping's global type/value in a resolve order
+const ResolveGlobal = "global"
+
+// defaultResolveOrder is the environment-specific spec, then the global fallback
+func defaultResolveOrder(env Environment) []string {
+	return []string{string(env), ResolveGlobal}
+}
+
+// ResolveValueSpec returns the first spec present on the mapping in order,
+// where each entry is "local", "docker" or "global"
+func (m *Mapping) ResolveValueSpec(order []string) *ValueSpec {
+	spec, _ := m.ResolveValueSpecSource(order)
+	return spec
+}
+
+// ResolveValueSpecSource is ResolveValueSpec that also reports which entry
+// of order supplied the spec, or "" if none did
+func (m *Mapping) ResolveValueSpecSource(order []string) (*ValueSpec, string) {
+	for _, source := range order {
+		switch source {
+		case string(EnvLocal):
+			if m.Local != nil {
+				return m.Local, source
+			}
+		case string(EnvDocker):
+			if m.Docker != nil {
+				return m.Docker, source
+			}
+		case ResolveGlobal:
+			if m.Type != "" && m.Value != "" {
+				return &ValueSpec{
+					Type:   m.Type,
+					Value:  m.Value,
+					Binary: m.Binary,
+				}, source
+			}
+		}
+	}
+	return nil, ""
+}
+
+// ResolveOrderFor returns the resolve order used for env
+func (c *Config) ResolveOrderFor(env Environment) []string {
+	if order, ok := c.ResolveOrder[env]; ok {
+		return order
+	}
+	return defaultResolveOrder(env)
+}
+
+// ValueSpec returns the spec mapping resolves to for env, honouring the
+// config's resolveOrder for that environment
+func (c *Config) ValueSpec(mapping Mapping, env Environment) *ValueSpec {
+	return mapping.ResolveValueSpec(c.ResolveOrderFor(env))
+}
+
+// ValueSpecSource is ValueSpec that also reports where the spec came from:
+// "local", "docker", "global", or "" when the mapping has no value for env
+func (c *Config) ValueSpecSource(mapping Mapping, env Environment) (*ValueSpec, string) {
+	return mapping.ResolveValueSpecSource(c.ResolveOrderFor(env))
+}
+
+// validateResolveOrder checks every environment's order names known sources once
+func validateResolveOrder(orders map[Environment][]string) error {
+	for env, order := range orders {
+		if env != EnvLocal && env != EnvDocker {
+			return fmt.Errorf("resolveOrder: invalid environment %q: must be %q or %q", env, EnvLocal, EnvDocker)
+		}
+		if len(order) == 0 {
+			return fmt.Errorf("resolveOrder.%s: must list at least one source", env)
+		}
+		seen := make(map[string]bool, len(order))
+		for _, source := range order {
+			switch source {
+			case string(EnvLocal), string(EnvDocker), ResolveGlobal:
+			default:
+				return fmt.Errorf("resolveOrder.%s: invalid source %q: must be %q, %q or %q", env, source, EnvLocal, EnvDocker, ResolveGlobal)
+			}
+			if seen[source] {
+				return fmt.Errorf("resolveOrder.%s: %q listed more than once", env, source)
+			}
+			seen[source] = true
+		}
+	}
+	return nil
+}
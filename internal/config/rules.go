@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+)
+
+// ValueRules are optional checks a resolved value must pass
+type ValueRules struct {
+	MinLength int    `json:"minLength,omitempty"`
+	MaxLength int    `json:"maxLength,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
+}
+
+// Check returns a description of the first rule value breaks, or nil. The
+// message never includes the value itself.
+func (r *ValueRules) Check(value string) error {
+	if r == nil {
+		return nil
+	}
+	length := utf8.RuneCountInString(value)
+	if r.MinLength > 0 && length < r.MinLength {
+		return fmt.Errorf("value is %d characters, shorter than minLength %d", length, r.MinLength)
+	}
+	if r.MaxLength > 0 && length > r.MaxLength {
+		return fmt.Errorf("value is %d characters, longer than maxLength %d", length, r.MaxLength)
+	}
+	if r.Pattern != "" {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", r.Pattern, err)
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("value does not match pattern %s", r.Pattern)
+		}
+	}
+	return nil
+}
+
+// validateRules checks a mapping's rules are usable
+func validateRules(key string, r *ValueRules) error {
+	if r == nil {
+		return nil
+	}
+	if r.MinLength < 0 || r.MaxLength < 0 {
+		return fmt.Errorf("validate for %s: lengths cannot be negative", key)
+	}
+	if r.MaxLength > 0 && r.MinLength > r.MaxLength {
+		return fmt.Errorf("validate for %s: minLength %d exceeds maxLength %d", key, r.MinLength, r.MaxLength)
+	}
+	if r.Pattern != "" {
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return fmt.Errorf("validate for %s: invalid pattern %q: %w", key, r.Pattern, err)
+		}
+	}
+	return nil
+}
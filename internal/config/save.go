@@ -0,0 +1,61 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Marshal returns the config in the canonical form Save writes: indented
+// JSON with fields in a fixed order, mappings sorted by key and each mapping
+// in its shortest form
+func Marshal(cfg *Config) ([]byte, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+
+	saved := *cfg
+	saved.Mappings = forgetReferencesOutside(cfg.Mappings, cfg.KeyVaultName)
+	data, err := json.MarshalIndent(&saved, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// Save writes the config as indented JSON to path atomically
+func Save(cfg *Config, path string) error {
+	data, err := Marshal(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Create temp file in same directory for atomic write
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // Clean up on any error
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set permissions on temp file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	tmp.Close()
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write config file %s: %w", path, err)
+	}
+
+	return nil
+}
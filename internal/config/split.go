@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SplitSpec picks one part of a composite Key Vault value such as
+// user:pass@host, so several keys can map to the same secret. It either
+// splits on Delimiter and takes the part at Index, or matches Pattern and
+// takes the capture group Group.
+type SplitSpec struct {
+	Delimiter string `json:"delimiter,omitempty"`
+	Index     int    `json:"index,omitempty"`
+	// Parts, if set, is the number of parts the value must split into
+	Parts int `json:"parts,omitempty"`
+
+	Pattern string `json:"pattern,omitempty"`
+	// Group is a capture group number or name (default 1)
+	Group string `json:"group,omitempty"`
+}
+
+// validate checks the spec is complete and its pattern and group exist
+func (s *SplitSpec) validate() error {
+	switch {
+	case s.Delimiter == "" && s.Pattern == "":
+		return fmt.Errorf("must set delimiter or pattern")
+	case s.Delimiter != "" && s.Pattern != "":
+		return fmt.Errorf("delimiter and pattern cannot both be set")
+	}
+
+	if s.Delimiter != "" {
+		if s.Group != "" {
+			return fmt.Errorf("group is only used with pattern")
+		}
+		if s.Index < 0 || s.Parts < 0 {
+			return fmt.Errorf("index and parts must not be negative")
+		}
+		if s.Parts > 0 && s.Index >= s.Parts {
+			return fmt.Errorf("index %d is out of range for %d parts", s.Index, s.Parts)
+		}
+		return nil
+	}
+
+	if s.Index != 0 || s.Parts != 0 {
+		return fmt.Errorf("index and parts are only used with delimiter")
+	}
+	re, err := regexp.Compile(s.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", s.Pattern, err)
+	}
+	_, err = s.groupIndex(re)
+	return err
+}
+
+// groupIndex resolves Group to a submatch index of re
+func (s *SplitSpec) groupIndex(re *regexp.Regexp) (int, error) {
+	group := s.Group
+	if group == "" {
+		group = "1"
+	}
+	if n, err := strconv.Atoi(group); err == nil {
+		if n < 0 || n > re.NumSubexp() {
+			return 0, fmt.Errorf("pattern %q has no group %d", s.Pattern, n)
+		}
+		return n, nil
+	}
+	if n := re.SubexpIndex(group); n >= 0 {
+		return n, nil
+	}
+	return 0, fmt.Errorf("pattern %q has no group named %q", s.Pattern, group)
+}
+
+// Apply returns the selected part of value. Errors describe the shape of the
+// value but never include it.
+func (s *SplitSpec) Apply(value string) (string, error) {
+	if s.Delimiter != "" {
+		parts := strings.Split(value, s.Delimiter)
+		if s.Parts > 0 && len(parts) != s.Parts {
+			return "", fmt.Errorf("splitting on %q gives %d parts, want %d", s.Delimiter, len(parts), s.Parts)
+		}
+		if s.Index >= len(parts) {
+			return "", fmt.Errorf("splitting on %q gives %d parts, no part at index %d", s.Delimiter, len(parts), s.Index)
+		}
+		return parts[s.Index], nil
+	}
+
+	re, err := regexp.Compile(s.Pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern %q: %w", s.Pattern, err)
+	}
+	n, err := s.groupIndex(re)
+	if err != nil {
+		return "", err
+	}
+	match := re.FindStringSubmatch(value)
+	if match == nil {
+		return "", fmt.Errorf("value does not match pattern %q", s.Pattern)
+	}
+	return match[n], nil
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TrimMode controls whitespace trimming of fetched secret values
+type TrimMode string
+
+const (
+	TrimNone     TrimMode = "none"
+	TrimTrailing TrimMode = "trailing"
+	TrimAll      TrimMode = "all"
+)
+
+// ParseTrimMode parses a trim mode name; "" is TrimNone
+func ParseTrimMode(s string) (TrimMode, error) {
+	switch TrimMode(s) {
+	case "", TrimNone:
+		return TrimNone, nil
+	case TrimTrailing, TrimAll:
+		return TrimMode(s), nil
+	default:
+		return "", fmt.Errorf("invalid trim mode %q: must be %s, %s or %s", s, TrimNone, TrimTrailing, TrimAll)
+	}
+}
+
+// Apply returns value trimmed according to the mode
+func (t TrimMode) Apply(value string) string {
+	switch t {
+	case TrimTrailing:
+		return strings.TrimRight(value, " \t\r\n")
+	case TrimAll:
+		return strings.TrimSpace(value)
+	default:
+		return value
+	}
+}
+
+// TrimModeOr returns the mapping's trim mode, or def when it does not set one
+func (m *Mapping) TrimModeOr(def TrimMode) TrimMode {
+	if m.Trim != "" {
+		return m.Trim
+	}
+	return def
+}
@@ -0,0 +1,114 @@
+package envwriter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Section is one environment's variables in a combined env file
+type Section struct {
+	Name string
+	Vars map[string]string
+}
+
+// sectionMarker returns the comment line that starts a section
+func sectionMarker(name string) string {
+	return "# [" + name + "]"
+}
+
+// sectionName returns the section a marker line starts, if it is one
+func sectionName(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "# [") || !strings.HasSuffix(line, "]") {
+		return "", false
+	}
+	return line[len("# [") : len(line)-1], true
+}
+
+// WriteCombinedFile atomically writes every section to one file, each under a
+// "# [name]" marker, using opts for the file's mode and owner
+func WriteCombinedFile(path string, sections []Section, header string, opts WriteOptions) error {
+	staged, err := stageFile(path, header, opts, func(tmp *os.File) error {
+		for i, section := range sections {
+			prefix := ""
+			if i > 0 {
+				prefix = "\n"
+			}
+			if _, err := tmp.WriteString(prefix + sectionMarker(section.Name) + "\n"); err != nil {
+				return err
+			}
+			if err := writeVars(tmp, section.Vars, opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	defer staged.Discard()
+
+	if opts.Verify {
+		staged.check = func(path string) error {
+			for _, section := range sections {
+				got, err := ReadSection(path, section.Name)
+				if err != nil {
+					return fmt.Errorf("failed to re-read %s: %w", path, err)
+				}
+				if err := compareValues(fmt.Sprintf("%s [%s]", path, section.Name), section.Vars, got); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return staged.Commit()
+}
+
+// ExtractSection returns the lines of the named section of a combined file.
+// It fails if the section does not exist.
+func ExtractSection(r io.Reader, name string) (string, error) {
+	var b strings.Builder
+	found, inSection := false, false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if section, ok := sectionName(line); ok {
+			inSection = section == name
+			found = found || inSection
+			continue
+		}
+		if inSection {
+			b.WriteString(line + "\n")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("no [%s] section", name)
+	}
+	return b.String(), nil
+}
+
+// ReadSection reads the key-value pairs of one section of a combined file. A
+// missing file or section reads as empty, like ReadKeyValues.
+func ReadSection(path, name string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]string), nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	body, err := ExtractSection(file, name)
+	if err != nil {
+		return make(map[string]string), nil
+	}
+	return parseKeyValues(strings.NewReader(body))
+}
@@ -0,0 +1,131 @@
+package envwriter
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strings"
+)
+
+// headerStart is the first line of the header yeet writes above generated files
+const headerStart = "# Generated by yeet"
+
+// writePreserved rewrites the existing file at path in place: comments, blank
+// lines and key order are kept, values in vars replace the ones on disk, keys
+// not in vars are dropped and new keys are appended sorted. A key's "export "
+// prefix is kept, and an UnmanagedComment is only kept while the key below it
+// is still unmanaged. The old yeet header is dropped since a fresh one is
+// written above it.
+func writePreserved(tmp *os.File, path string, vars map[string]string, opts WriteOptions) error {
+	lines, err := readLines(path)
+	if err != nil {
+		return err
+	}
+	if lines == nil {
+		return writeVars(tmp, vars, opts)
+	}
+
+	written := make(map[string]bool, len(vars))
+	prev := ""
+	emit := func(line string) error {
+		prev = line
+		_, err := tmp.WriteString(line + "\n")
+		return err
+	}
+	emitVar := func(prefix, key string) error {
+		if opts.Unmanaged[key] && prev != UnmanagedComment {
+			if err := emit(UnmanagedComment); err != nil {
+				return err
+			}
+		}
+		written[key] = true
+		return emit(prefix + FormatLine(key, vars[key]))
+	}
+
+	// An UnmanagedComment is held back until the line after it shows whether
+	// it still belongs there; emitVar writes it again for unmanaged keys
+	pendingComment := false
+	for _, line := range stripHeader(lines) {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == UnmanagedComment {
+			if pendingComment {
+				if err := emit(UnmanagedComment); err != nil {
+					return err
+				}
+			}
+			pendingComment = true
+			continue
+		}
+
+		matches := envLineRegex.FindStringSubmatch(trimmed)
+		if len(matches) < 2 {
+			if pendingComment {
+				pendingComment = false
+				if err := emit(UnmanagedComment); err != nil {
+					return err
+				}
+			}
+			if err := emit(line); err != nil {
+				return err
+			}
+			continue
+		}
+		pendingComment = false
+		key := matches[1]
+		if _, ok := vars[key]; !ok || written[key] {
+			continue
+		}
+		prefix := strings.TrimSuffix(matches[0], key+"=")
+		if err := emitVar(prefix, key); err != nil {
+			return err
+		}
+	}
+
+	var added []string
+	for key := range vars {
+		if !written[key] {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+	for _, key := range added {
+		if err := emitVar("", key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readLines returns the lines of path, or nil if it does not exist
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// stripHeader drops a leading yeet header comment block and the blank line after it
+func stripHeader(lines []string) []string {
+	if len(lines) == 0 || lines[0] != headerStart {
+		return lines
+	}
+	i := 0
+	for i < len(lines) && strings.HasPrefix(lines[i], "#") {
+		i++
+	}
+	if i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+	return lines[i:]
+}
@@ -0,0 +1,87 @@
+package envwriter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWritePreserved(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  string
+		vars      map[string]string
+		unmanaged map[string]bool
+		want      string
+	}{
+		{
+			name:     "keeps comments, order and new keys last",
+			existing: "# db\nB=old\n\nA=1\n",
+			vars:     map[string]string{"A": "1", "B": "new", "C": "3"},
+			want:     "# db\nB=new\n\nA=1\nC=3\n",
+		},
+		{
+			name:     "keeps export prefix",
+			existing: "export A=old\nB=1\n",
+			vars:     map[string]string{"A": "new", "B": "1"},
+			want:     "export A=new\nB=1\n",
+		},
+		{
+			name:     "drops exported key",
+			existing: "export OLD=1\nA=1\n",
+			vars:     map[string]string{"A": "1"},
+			want:     "A=1\n",
+		},
+		{
+			name:     "drops unmanaged comment with its key",
+			existing: "X=1\n" + UnmanagedComment + "\nU=2\nY=3\n",
+			vars:     map[string]string{"X": "1", "Y": "3"},
+			want:     "X=1\nY=3\n",
+		},
+		{
+			name:     "drops unmanaged comment when key becomes managed",
+			existing: UnmanagedComment + "\nU=2\n",
+			vars:     map[string]string{"U": "2"},
+			want:     "U=2\n",
+		},
+		{
+			name:      "keeps unmanaged comment once",
+			existing:  UnmanagedComment + "\nU=2\n",
+			vars:      map[string]string{"U": "2"},
+			unmanaged: map[string]bool{"U": true},
+			want:      UnmanagedComment + "\nU=2\n",
+		},
+		{
+			name:      "marks newly unmanaged key",
+			existing:  "U=2\n",
+			vars:      map[string]string{"U": "2"},
+			unmanaged: map[string]bool{"U": true},
+			want:      UnmanagedComment + "\nU=2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".env")
+			if err := os.WriteFile(path, []byte(tt.existing), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			opts := DefaultWriteOptions()
+			opts.Preserve = true
+			opts.Verify = true
+			opts.Unmanaged = tt.unmanaged
+			if err := WriteEnvFileWithOptions(path, tt.vars, "", opts); err != nil {
+				t.Fatalf("WriteEnvFileWithOptions: %v", err)
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
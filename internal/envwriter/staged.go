@@ -0,0 +1,150 @@
+package envwriter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// StagedFile is a fully written temp file waiting to replace its target path
+type StagedFile struct {
+	path      string
+	tmpPath   string
+	mode      os.FileMode
+	committed bool
+
+	// check, if set, re-reads the committed file; on failure Commit restores
+	// the previous file
+	check func(path string) error
+}
+
+// Path returns the file's final destination
+func (s *StagedFile) Path() string {
+	return s.path
+}
+
+// Commit atomically renames the staged file over its target
+func (s *StagedFile) Commit() error {
+	var prev *backup
+	if s.check != nil {
+		b, err := takeBackup(s.path)
+		if err != nil {
+			return err
+		}
+		prev = b
+	}
+
+	if err := os.Rename(s.tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	s.committed = true
+	if err := verifyMode(s.path, s.mode); err != nil {
+		return err
+	}
+
+	if s.check != nil {
+		if err := s.check(s.path); err != nil {
+			if rbErr := prev.restore(); rbErr != nil {
+				return fmt.Errorf("%w (restoring the previous file also failed: %v)", err, rbErr)
+			}
+			return fmt.Errorf("%w (previous file restored)", err)
+		}
+	}
+	return nil
+}
+
+// Discard removes the temp file if it has not been committed
+func (s *StagedFile) Discard() {
+	if !s.committed {
+		os.Remove(s.tmpPath)
+	}
+}
+
+// CommitAll commits every staged file or none of them: if any rename fails,
+// targets already replaced are restored to their previous contents (or
+// removed if they did not exist before)
+func CommitAll(files ...*StagedFile) error {
+	backups := make([]*backup, len(files))
+	for i, f := range files {
+		b, err := takeBackup(f.path)
+		if err != nil {
+			return err
+		}
+		backups[i] = b
+	}
+
+	for i, f := range files {
+		if err := f.Commit(); err != nil {
+			var rollbackErrs []error
+			for j := i; j >= 0; j-- {
+				if files[j].committed {
+					if rbErr := backups[j].restore(); rbErr != nil {
+						rollbackErrs = append(rollbackErrs, rbErr)
+					}
+				}
+			}
+			if len(rollbackErrs) > 0 {
+				return fmt.Errorf("%w (rollback also failed: %v)", err, errors.Join(rollbackErrs...))
+			}
+			return fmt.Errorf("%w (all files left unchanged)", err)
+		}
+	}
+	return nil
+}
+
+// backup holds a target file's contents from before a commit
+type backup struct {
+	path    string
+	existed bool
+	data    []byte
+	mode    os.FileMode
+}
+
+func takeBackup(path string) (*backup, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return &backup{path: path}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	return &backup{path: path, existed: true, data: data, mode: info.Mode().Perm()}, nil
+}
+
+func (b *backup) restore() error {
+	if !b.existed {
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	// Write the old contents the way stageFile does, so a failed restore
+	// never leaves a truncated file behind
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), ".env-tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(b.mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(b.data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), b.path)
+}
@@ -4,66 +4,199 @@ import (
 	"bufio"
 	"fmt"
 	"github.com/JayDubyaEey/yeet/internal/config"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"sort"
 	"strings"
 )
 
-var envLineRegex = regexp.MustCompile(`^([A-Z_][A-Z0-9_]*)=`)
+// envLineRegex is deliberately looser than the config name pattern so keys
+// accepted by a custom namePattern are still recognised when re-reading files.
+// A leading "export " is allowed, as in files that are also sourced by shells.
+var envLineRegex = regexp.MustCompile(`^(?:export\s+)?([A-Za-z_][A-Za-z0-9_.-]*)=`)
 
-// WriteEnvFile writes env vars to a file atomically
+// UnmanagedComment is written above keys listed in WriteOptions.Unmanaged
+const UnmanagedComment = "# unmanaged (retained by yeet)"
+
+// WriteOptions controls the permissions and presentation of written env files
+type WriteOptions struct {
+	Mode os.FileMode // file mode applied before the file is moved into place
+	UID  int         // owner uid, or -1 to leave unchanged
+	GID  int         // owner gid, or -1 to leave unchanged
+
+	Unmanaged map[string]bool // keys to mark with UnmanagedComment
+
+	// Preserve keeps the existing file's comments, blank lines and key order,
+	// updating values in place instead of regenerating the file sorted
+	Preserve bool
+
+	// Verify re-reads the file after it is moved into place and restores the
+	// previous file if any value does not read back exactly as written
+	Verify bool
+}
+
+// DefaultWriteOptions returns owner-only permissions, since env files hold secrets
+func DefaultWriteOptions() WriteOptions {
+	return WriteOptions{Mode: 0600, UID: -1, GID: -1}
+}
+
+// WriteEnvFile writes env vars to a file atomically with default permissions
 func WriteEnvFile(path string, vars map[string]string, header string) error {
+	return WriteEnvFileWithOptions(path, vars, header, DefaultWriteOptions())
+}
+
+// WriteEnvFileWithOptions writes env vars to a file atomically using opts for
+// the resulting file's mode and owner
+func WriteEnvFileWithOptions(path string, vars map[string]string, header string, opts WriteOptions) error {
+	staged, err := StageEnvFile(path, vars, header, opts)
+	if err != nil {
+		return err
+	}
+	defer staged.Discard()
+
+	return staged.Commit()
+}
+
+// StageEnvFile writes env vars to a temp file next to path without replacing
+// path; call Commit to move it into place or Discard to throw it away
+func StageEnvFile(path string, vars map[string]string, header string, opts WriteOptions) (*StagedFile, error) {
+	staged, err := stageFile(path, header, opts, func(tmp *os.File) error {
+		if opts.Preserve {
+			return writePreserved(tmp, path, vars, opts)
+		}
+		return writeVars(tmp, vars, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if opts.Verify {
+		staged.check = func(path string) error {
+			got, err := ReadKeyValues(path)
+			if err != nil {
+				return fmt.Errorf("failed to re-read %s: %w", path, err)
+			}
+			return compareValues(path, vars, got)
+		}
+	}
+	return staged, nil
+}
+
+// compareValues fails if any key in want is missing from got or differs.
+// The error names keys only, never values.
+func compareValues(path string, want, got map[string]string) error {
+	var bad []string
+	for key, value := range want {
+		if v, ok := got[key]; !ok || v != value {
+			bad = append(bad, key)
+		}
+	}
+	if len(bad) == 0 {
+		return nil
+	}
+	sort.Strings(bad)
+	return fmt.Errorf("%s did not read back as written for %d keys: %s", path, len(bad), strings.Join(bad, ", "))
+}
+
+// stageFile writes header and body to a locked-down temp file next to path
+func stageFile(path, header string, opts WriteOptions, body func(*os.File) error) (*StagedFile, error) {
 	// Create temp file in same directory for atomic write
 	dir := filepath.Dir(path)
 	tmp, err := os.CreateTemp(dir, ".env-tmp-*")
 	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	staged := &StagedFile{path: path, tmpPath: tmp.Name(), mode: opts.Mode}
+
+	if err := writeStaged(tmp, header, opts, body); err != nil {
+		tmp.Close()
+		staged.Discard()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		staged.Discard()
+		return nil, err
+	}
+
+	return staged, nil
+}
+
+func writeStaged(tmp *os.File, header string, opts WriteOptions, body func(*os.File) error) error {
+	// Lock the temp file down before any secret is written, regardless of umask
+	if err := tmp.Chmod(0600); err != nil {
+		return fmt.Errorf("failed to secure temp file: %w", err)
 	}
-	tmpPath := tmp.Name()
-	defer os.Remove(tmpPath) // Clean up on any error
 
 	// Write header
 	if header != "" {
 		if _, err := tmp.WriteString(header + "\n"); err != nil {
-			tmp.Close()
 			return err
 		}
 	}
 
-	// Sort keys for stable output
+	if err := body(tmp); err != nil {
+		return err
+	}
+
+	// Sync to disk
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+
+	// Apply permissions before the file becomes visible at its final path
+	if err := tmp.Chmod(opts.Mode); err != nil {
+		return fmt.Errorf("failed to set mode on %s: %w", tmp.Name(), err)
+	}
+	if opts.UID != -1 || opts.GID != -1 {
+		if err := tmp.Chown(opts.UID, opts.GID); err != nil {
+			return fmt.Errorf("failed to set owner on %s: %w", tmp.Name(), err)
+		}
+	}
+	return nil
+}
+
+// writeVars writes vars sorted by key
+func writeVars(tmp *os.File, vars map[string]string, opts WriteOptions) error {
 	keys := make([]string, 0, len(vars))
 	for k := range vars {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
 
-	// Write each var
 	for _, key := range keys {
-		value := vars[key]
-		line := fmt.Sprintf("%s=%s\n", key, quoteValue(value))
+		line := FormatLine(key, vars[key]) + "\n"
+		if opts.Unmanaged[key] {
+			line = UnmanagedComment + "\n" + line
+		}
 		if _, err := tmp.WriteString(line); err != nil {
-			tmp.Close()
 			return err
 		}
 	}
+	return nil
+}
 
-	// Sync to disk
-	if err := tmp.Sync(); err != nil {
-		tmp.Close()
-		return err
+// verifyMode confirms the written file ended up with the expected permissions
+func verifyMode(path string, want os.FileMode) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
 	}
-	tmp.Close()
-
-	// Atomic rename
-	if err := os.Rename(tmpPath, path); err != nil {
-		return fmt.Errorf("failed to rename temp file: %w", err)
+	if runtime.GOOS == "windows" {
+		return nil // Windows only tracks the read-only bit
+	}
+	if got := info.Mode().Perm(); got != want.Perm() {
+		return fmt.Errorf("%s has mode %04o, expected %04o", path, got, want.Perm())
 	}
-
 	return nil
 }
 
+// FormatLine renders a single KEY=value line, quoting the value when needed
+func FormatLine(key, value string) string {
+	return fmt.Sprintf("%s=%s", key, quoteValue(value))
+}
+
 // quoteValue adds quotes if the value contains special characters
 func quoteValue(value string) string {
 	// Check if quoting is needed
@@ -93,8 +226,13 @@ func ReadKeyValues(path string) (map[string]string, error) {
 	}
 	defer file.Close()
 
+	return parseKeyValues(file)
+}
+
+// parseKeyValues reads KEY=VALUE lines, skipping comments and blank lines
+func parseKeyValues(r io.Reader) (map[string]string, error) {
 	vars := make(map[string]string)
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := scanner.Text()
 		line = strings.TrimSpace(line)
@@ -104,16 +242,53 @@ func ReadKeyValues(path string) (map[string]string, error) {
 			continue
 		}
 
-		// Extract key (we only care about keys for unmapped detection)
 		matches := envLineRegex.FindStringSubmatch(line)
 		if len(matches) > 1 {
-			vars[matches[1]] = "" // Value doesn't matter for our use case
+			vars[matches[1]] = unquoteValue(line[len(matches[0]):])
 		}
 	}
 
 	return vars, scanner.Err()
 }
 
+// unquoteValue reverses quoteValue
+func unquoteValue(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return value
+	}
+
+	value = value[1 : len(value)-1]
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i == len(value)-1 {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+// CountChanged returns how many keys in newVars are absent from or differ in existingVars
+func CountChanged(newVars, existingVars map[string]string) int {
+	changed := 0
+	for k, v := range newVars {
+		if old, ok := existingVars[k]; !ok || old != v {
+			changed++
+		}
+	}
+	return changed
+}
+
 // MergeRetainUnknowns merges new values with existing, retaining unmapped keys
 func MergeRetainUnknowns(newVars, existingVars map[string]string, mappings map[string]config.Mapping) map[string]string {
 	result := make(map[string]string)
@@ -0,0 +1,35 @@
+package envwriter
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWriteEnvFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows only tracks the read-only bit")
+	}
+
+	path := filepath.Join(t.TempDir(), ".env")
+	// A pre-existing, more permissive file must not keep its mode
+	if err := os.WriteFile(path, []byte("OLD=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteEnvFile(path, map[string]string{"KEY": "value"}, "# header"); err != nil {
+		t.Fatalf("WriteEnvFile: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode().Perm(); got != 0600 {
+		t.Errorf("mode = %04o, want 0600", got)
+	}
+}
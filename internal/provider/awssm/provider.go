@@ -0,0 +1,232 @@
+// Package awssm implements provider.Provider with AWS Secrets Manager through
+// the AWS SDK.
+//
+// The config's keyVaultName (the vault argument) is an optional prefix put in
+// front of every secret name as-is, so "myapp/" and "db-password" read the
+// secret myapp/db-password. Credentials and, unless a region is given, the
+// region come from the SDK's default chain (AWS_REGION, AWS_PROFILE,
+// ~/.aws/config, instance roles).
+package awssm
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+
+	"github.com/JayDubyaEey/yeet/internal/provider"
+)
+
+// Provider implements secret operations using the AWS SDK
+type Provider struct {
+	timeout time.Duration
+	region  string
+
+	// The SDK config is loaded on first use, since that can fail and New cannot
+	once    sync.Once
+	cfg     aws.Config
+	loadErr error
+}
+
+var (
+	_ provider.Provider           = (*Provider)(nil)
+	_ provider.Lister             = (*Provider)(nil)
+	_ provider.VaultAccessChecker = (*Provider)(nil)
+	_ provider.LoginHinter        = (*Provider)(nil)
+)
+
+// New creates an AWS Secrets Manager provider; an empty region leaves it to
+// the SDK's default configuration
+func New(region string) *Provider {
+	return &Provider{
+		timeout: 30 * time.Second,
+		region:  region,
+	}
+}
+
+// AuthError indicates the AWS credentials are missing, expired or lack permission
+type AuthError struct {
+	Prefix string
+	Err    error
+}
+
+func (e *AuthError) Error() string {
+	if e.Prefix == "" {
+		return fmt.Sprintf("AWS credentials are missing, expired or not allowed: %v", e.Err)
+	}
+	return fmt.Sprintf("AWS credentials are missing, expired or not allowed to read %s*: %v", e.Prefix, e.Err)
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// Is lets errors.Is match provider.ErrAuth
+func (e *AuthError) Is(target error) bool {
+	return target == provider.ErrAuth
+}
+
+// authErrorCodes are the API error codes for bad or insufficient credentials
+var authErrorCodes = map[string]bool{
+	"AccessDeniedException":       true,
+	"UnrecognizedClientException": true,
+	"ExpiredTokenException":       true,
+	"InvalidSignatureException":   true,
+	"InvalidClientTokenId":        true,
+	"ExpiredToken":                true,
+}
+
+// awsConfig loads the SDK config once, applying the region override
+func (p *Provider) awsConfig(ctx context.Context) (aws.Config, error) {
+	p.once.Do(func() {
+		var opts []func(*awsconfig.LoadOptions) error
+		if p.region != "" {
+			opts = append(opts, awsconfig.WithRegion(p.region))
+		}
+		p.cfg, p.loadErr = awsconfig.LoadDefaultConfig(ctx, opts...)
+		if p.loadErr != nil {
+			p.loadErr = fmt.Errorf("failed to load AWS configuration: %w", p.loadErr)
+		}
+	})
+	return p.cfg, p.loadErr
+}
+
+func (p *Provider) client(ctx context.Context) (*secretsmanager.Client, error) {
+	cfg, err := p.awsConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return secretsmanager.NewFromConfig(cfg), nil
+}
+
+// EnsureLoggedIn checks that usable credentials are available
+func (p *Provider) EnsureLoggedIn(ctx context.Context) error {
+	cfg, err := p.awsConfig(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	if _, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{}); err != nil {
+		return &AuthError{Err: err}
+	}
+	return nil
+}
+
+// LoginHint implements provider.LoginHinter
+func (p *Provider) LoginHint() string {
+	return "aws sso login, or set AWS credentials"
+}
+
+// GetSecret retrieves a secret value. Binary secrets are returned base64
+// encoded, which is what mappings marked binary expect.
+func (p *Provider) GetSecret(ctx context.Context, prefix, name string) (string, error) {
+	client, err := p.client(ctx)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	id := prefix + name
+	out, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+	if err != nil {
+		return "", classifyError(err, prefix, id)
+	}
+	if out.SecretString != nil {
+		return *out.SecretString, nil
+	}
+	return base64.StdEncoding.EncodeToString(out.SecretBinary), nil
+}
+
+// SecretExists checks if a secret exists; one pending deletion does not
+func (p *Provider) SecretExists(ctx context.Context, prefix, name string) (bool, error) {
+	_, err := p.GetSecret(ctx, prefix, name)
+	if err != nil {
+		if provider.IsNotFound(err) || provider.IsDisabled(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ListSecrets lists the names of secrets under prefix, with the prefix removed
+func (p *Provider) ListSecrets(ctx context.Context, prefix string) ([]string, error) {
+	client, err := p.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	input := &secretsmanager.ListSecretsInput{}
+	if prefix != "" {
+		input.Filters = []types.Filter{{Key: types.FilterNameStringTypeName, Values: []string{prefix}}}
+	}
+
+	var listed []string
+	pages := secretsmanager.NewListSecretsPaginator(client, input)
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, classifyError(err, prefix, "")
+		}
+		// The name filter matches prefixes of any word in the name, so check it again
+		for _, entry := range page.SecretList {
+			if rest, ok := strings.CutPrefix(aws.ToString(entry.Name), prefix); ok && rest != "" {
+				listed = append(listed, rest)
+			}
+		}
+	}
+	return listed, nil
+}
+
+// CheckVaultAccess lists at most one secret, which is enough to tell missing
+// permissions apart from missing credentials
+func (p *Provider) CheckVaultAccess(ctx context.Context, prefix string) error {
+	client, err := p.client(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	if _, err := client.ListSecrets(ctx, &secretsmanager.ListSecretsInput{MaxResults: aws.Int32(1)}); err != nil {
+		return classifyError(err, prefix, "")
+	}
+	return nil
+}
+
+// classifyError matches an SDK error to provider.ErrNotFound, ErrDisabled or
+// ErrAuth where its type or error code allows; id names the secret for not
+// found errors
+func classifyError(err error, prefix, id string) error {
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return fmt.Errorf("secret %s not found: %w", id, provider.ErrNotFound)
+	}
+	var invalid *types.InvalidRequestException
+	if errors.As(err, &invalid) && strings.Contains(invalid.ErrorMessage(), "marked for deletion") {
+		return fmt.Errorf("secret %s is scheduled for deletion; restore it or map the key to another secret: %w", id, provider.ErrDisabled)
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && authErrorCodes[apiErr.ErrorCode()] {
+		return &AuthError{Prefix: prefix, Err: err}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("AWS Secrets Manager request timed out: %w", err)
+	}
+	return fmt.Errorf("AWS Secrets Manager request failed: %w", err)
+}
@@ -0,0 +1,133 @@
+package azcli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/JayDubyaEey/yeet/internal/provider"
+)
+
+// VaultNotFoundError indicates the Key Vault name did not resolve to a vault
+type VaultNotFoundError struct {
+	Vault string
+}
+
+func (e *VaultNotFoundError) Error() string {
+	return fmt.Sprintf("vault %s not found; check --vault / keyVaultName spelling", e.Vault)
+}
+
+// Is lets errors.Is match provider.ErrVaultNotFound
+func (e *VaultNotFoundError) Is(target error) bool {
+	return target == provider.ErrVaultNotFound
+}
+
+// AuthError indicates the Azure CLI session is missing, expired or lacks permission
+type AuthError struct {
+	Vault     string
+	Forbidden bool
+	Stderr    string
+}
+
+func (e *AuthError) Error() string {
+	if e.Forbidden {
+		return fmt.Sprintf("access denied to vault %s; ensure you have the Key Vault Secrets User role", e.Vault)
+	}
+	return "Azure CLI session is missing or expired (run: yeet login)"
+}
+
+// Is lets errors.Is match provider.ErrAuth
+func (e *AuthError) Is(target error) bool {
+	return target == provider.ErrAuth
+}
+
+// DisabledError indicates the secret exists but is disabled
+type DisabledError struct {
+	Secret string
+	Vault  string
+}
+
+func (e *DisabledError) Error() string {
+	return fmt.Sprintf("secret %s in vault %s is disabled; enable it or map the key to another secret", e.Secret, e.Vault)
+}
+
+// Is lets errors.Is match provider.ErrDisabled
+func (e *DisabledError) Is(target error) bool {
+	return target == provider.ErrDisabled
+}
+
+// NetworkError indicates a transient connectivity failure talking to the vault
+type NetworkError struct {
+	Vault  string
+	Stderr string
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("network error reaching vault %s: %s", e.Vault, firstLine(e.Stderr))
+}
+
+// IsVaultNotFound checks if the error is a vault not found error
+func IsVaultNotFound(err error) bool {
+	var target *VaultNotFoundError
+	return errors.As(err, &target)
+}
+
+// IsAuth checks if the error is an authentication or authorization error
+func IsAuth(err error) bool {
+	var target *AuthError
+	return errors.As(err, &target)
+}
+
+// IsNetwork checks if the error is a transient network error
+func IsNetwork(err error) bool {
+	var target *NetworkError
+	return errors.As(err, &target)
+}
+
+var (
+	secretNotFoundSignatures = []string{"SecretNotFound", "(404)"}
+	secretDisabledSignatures = []string{"SecretDisabled", "disabled secret"}
+	vaultNotFoundSignatures  = []string{"VaultNotFound", "could not be resolved", "Name or service not known", "getaddrinfo failed", "nodename nor servname", "no such host"}
+	forbiddenSignatures      = []string{"Forbidden", "(403)", "AccessDenied"}
+	authSignatures           = []string{"az login", "AADSTS", "expired", "Unauthorized", "(401)"}
+	networkSignatures        = []string{"timed out", "Timeout", "Connection reset", "Connection aborted", "Connection refused", "Temporary failure in name resolution", "Max retries exceeded"}
+)
+
+// classifyError maps az stderr output to a typed error, or returns nil if it
+// matches no known signature. Order matters: a DNS failure is reported inside
+// a "Max retries exceeded" message, so vault lookups are checked before network.
+func classifyError(stderr, vault, name string) error {
+	switch {
+	case containsAny(stderr, secretNotFoundSignatures):
+		return &NotFoundError{Secret: name, Vault: vault}
+	case containsAny(stderr, secretDisabledSignatures):
+		// Reported as 403 Forbidden, so it must be checked before access errors
+		return &DisabledError{Secret: name, Vault: vault}
+	case containsAny(stderr, vaultNotFoundSignatures):
+		return &VaultNotFoundError{Vault: vault}
+	case containsAny(stderr, forbiddenSignatures):
+		return &AuthError{Vault: vault, Forbidden: true, Stderr: stderr}
+	case containsAny(stderr, authSignatures):
+		return &AuthError{Vault: vault, Stderr: stderr}
+	case containsAny(stderr, networkSignatures):
+		return &NetworkError{Vault: vault, Stderr: stderr}
+	}
+	return nil
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -4,53 +4,120 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
-	"strings"
+	"path"
 	"time"
+
+	"github.com/JayDubyaEey/yeet/internal/provider"
 )
 
 // Provider implements secret operations using Azure CLI
 type Provider struct {
-	timeout time.Duration
+	timeout    time.Duration
+	retries    int
+	retryDelay time.Duration
+
+	// dnsSuffix addresses vaults as https://VAULT.<dnsSuffix> (e.g. on Azure
+	// Stack); empty means the Azure CLI's cloud default
+	dnsSuffix string
 }
 
+var (
+	_ provider.Provider       = (*Provider)(nil)
+	_ provider.MetadataGetter = (*Provider)(nil)
+	_ provider.Lister         = (*Provider)(nil)
+	_ provider.Setter         = (*Provider)(nil)
+	_ provider.Deleter        = (*Provider)(nil)
+	_ provider.TokenWarmer    = (*Provider)(nil)
+	_ provider.LoginHinter    = (*Provider)(nil)
+)
+
 // NewDefault creates a new Azure CLI provider with default settings
 func NewDefault() *Provider {
 	return &Provider{
-		timeout: 30 * time.Second,
+		timeout:    30 * time.Second,
+		retries:    3,
+		retryDelay: 500 * time.Millisecond,
 	}
 }
 
+// NewWithDNSSuffix creates a provider that addresses vaults under a custom
+// Key Vault DNS suffix instead of the Azure CLI's cloud default
+func NewWithDNSSuffix(suffix string) *Provider {
+	p := NewDefault()
+	p.dnsSuffix = suffix
+	return p
+}
+
+// vaultArgs selects the vault for az: by name, or by full URL when a custom
+// DNS suffix is set since --vault-name always uses the cloud's suffix
+func (p *Provider) vaultArgs(vault string) []string {
+	if p.dnsSuffix == "" {
+		return []string{"--vault-name", vault}
+	}
+	return []string{"--id", "https://" + vault + "." + p.dnsSuffix}
+}
+
+// secretArgs selects a secret for az, like vaultArgs
+func (p *Provider) secretArgs(vault, name string) []string {
+	if p.dnsSuffix == "" {
+		return []string{"--vault-name", vault, "--name", name}
+	}
+	return []string{"--id", "https://" + vault + "." + p.dnsSuffix + "/secrets/" + name}
+}
+
 // EnsureLoggedIn checks if the user is logged into Azure CLI
 func (p *Provider) EnsureLoggedIn(ctx context.Context) error {
 	cmd := exec.CommandContext(ctx, "az", "account", "show", "-o", "none")
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("not logged in to Azure CLI")
+		return fmt.Errorf("no Azure CLI session")
 	}
 	return nil
 }
 
-// Login authenticates with Azure CLI
-func (p *Provider) Login(ctx context.Context, tenant, subscription string) error {
+// LoginHint implements provider.LoginHinter
+func (p *Provider) LoginHint() string {
+	return "yeet login"
+}
+
+// LoginOptions configures Login
+type LoginOptions struct {
+	Tenant       string
+	Subscription string // selected after logging in, if set
+	// UseDeviceCode logs in with a code entered on another device, for
+	// machines that cannot open a browser
+	UseDeviceCode bool
+}
+
+// Login authenticates with Azure CLI. az's prompts, including the device
+// code, are passed through to stderr.
+func (p *Provider) Login(ctx context.Context, opts LoginOptions) error {
 	args := []string{"login", "-o", "none"}
-	if tenant != "" {
-		args = append(args, "--tenant", tenant)
+	if opts.Tenant != "" {
+		args = append(args, "--tenant", opts.Tenant)
+	}
+	if opts.UseDeviceCode {
+		args = append(args, "--use-device-code")
 	}
 
 	cmd := exec.CommandContext(ctx, "az", args...)
 	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("az login failed: %w (stderr: %s)", err, stderr.String())
+		return fmt.Errorf("az login failed: %w (stderr: %s)", err, firstLine(stderr.String()))
 	}
 
 	// Set subscription if provided
-	if subscription != "" {
-		cmd := exec.CommandContext(ctx, "az", "account", "set", "--subscription", subscription, "-o", "none")
+	if opts.Subscription != "" {
+		cmd := exec.CommandContext(ctx, "az", "account", "set", "--subscription", opts.Subscription, "-o", "none")
 		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to set subscription %s: %w", subscription, err)
+			return fmt.Errorf("failed to set subscription %s: %w", opts.Subscription, err)
 		}
 	}
 
@@ -65,52 +132,243 @@ func (p *Provider) Logout(ctx context.Context) error {
 
 // GetSecret retrieves a secret value from Key Vault
 func (p *Provider) GetSecret(ctx context.Context, vault, name string) (string, error) {
+	secret, err := p.GetSecretWithMetadata(ctx, vault, name)
+	return secret.Value, err
+}
+
+// GetSecretWithMetadata retrieves a secret and its attributes from Key Vault,
+// retrying transient network failures
+func (p *Provider) GetSecretWithMetadata(ctx context.Context, vault, name string) (provider.Secret, error) {
+	var err error
+	for attempt := 1; attempt <= p.retries; attempt++ {
+		var secret provider.Secret
+		secret, err = p.getSecretOnce(ctx, vault, name)
+		if err == nil || !IsNetwork(err) || attempt == p.retries {
+			return secret, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return provider.Secret{}, ctx.Err()
+		case <-time.After(time.Duration(attempt) * p.retryDelay):
+		}
+	}
+	return provider.Secret{}, err
+}
+
+// secretBundle is the part of 'az keyvault secret show' output yeet reads
+type secretBundle struct {
+	ID          string            `json:"id"`
+	Value       string            `json:"value"`
+	ContentType string            `json:"contentType"`
+	Tags        map[string]string `json:"tags"`
+	Attributes  struct {
+		Enabled *bool  `json:"enabled"`
+		Updated string `json:"updated"`
+	} `json:"attributes"`
+}
+
+func (p *Provider) getSecretOnce(ctx context.Context, vault, name string) (provider.Secret, error) {
 	ctx, cancel := context.WithTimeout(ctx, p.timeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "az", "keyvault", "secret", "show",
-		"--vault-name", vault,
-		"--name", name,
-		"-o", "json")
+	args := append([]string{"keyvault", "secret", "show"}, p.secretArgs(vault, name)...)
+	cmd := exec.CommandContext(ctx, "az", append(args, "-o", "json")...)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		if strings.Contains(stderr.String(), "SecretNotFound") ||
-			strings.Contains(stderr.String(), "(404)") {
-			return "", &NotFoundError{Secret: name, Vault: vault}
+		if typed := classifyError(stderr.String(), vault, name); typed != nil {
+			return provider.Secret{}, typed
 		}
-		return "", fmt.Errorf("failed to get secret: %w (stderr: %s)", err, stderr.String())
+		if ctx.Err() == context.DeadlineExceeded {
+			return provider.Secret{}, &NetworkError{Vault: vault, Stderr: "request timed out"}
+		}
+		return provider.Secret{}, fmt.Errorf("failed to get secret: %w (stderr: %s)", err, stderr.String())
 	}
 
-	var result struct {
-		Value string `json:"value"`
-	}
+	var result secretBundle
 	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
-		return "", fmt.Errorf("failed to parse secret response: %w", err)
+		return provider.Secret{}, fmt.Errorf("failed to parse secret response: %w", err)
 	}
 
-	return result.Value, nil
+	secret := provider.Secret{
+		Value:       result.Value,
+		ContentType: result.ContentType,
+		Tags:        result.Tags,
+		Enabled:     result.Attributes.Enabled == nil || *result.Attributes.Enabled,
+	}
+	// A timestamp az formats unexpectedly is left zero rather than failing the read
+	if updated, err := time.Parse(time.RFC3339, result.Attributes.Updated); err == nil {
+		secret.Updated = updated
+	}
+	// The id ends in /secrets/NAME/VERSION
+	if result.ID != "" {
+		secret.Version = path.Base(result.ID)
+	}
+	return secret, nil
 }
 
-// SecretExists checks if a secret exists in Key Vault
+// SecretExists checks if a secret exists in Key Vault; a disabled secret exists
 func (p *Provider) SecretExists(ctx context.Context, vault, name string) (bool, error) {
 	_, err := p.GetSecret(ctx, vault, name)
 	if err != nil {
 		if IsNotFound(err) {
 			return false, nil
 		}
+		if provider.IsDisabled(err) {
+			return true, nil
+		}
 		return false, err
 	}
 	return true, nil
 }
 
+// setSecretAPIVersion is the Key Vault REST API version used to set secrets
+// under a custom DNS suffix
+const setSecretAPIVersion = "7.4"
+
+// SetVaultSecret creates or updates a secret. The value is passed through a
+// private temp file so it never appears in the process list. 'az keyvault
+// secret set' only takes --vault-name, so with a custom DNS suffix the secret
+// is written with 'az rest' to the suffixed endpoint instead.
+func (p *Provider) SetVaultSecret(ctx context.Context, vault, name, value string) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	content := value
+	if p.dnsSuffix != "" {
+		body, err := json.Marshal(map[string]string{"value": value})
+		if err != nil {
+			return err
+		}
+		content = string(body)
+	}
+
+	tmp, err := os.CreateTemp("", "yeet-secret-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	var cmd *exec.Cmd
+	if p.dnsSuffix == "" {
+		cmd = exec.CommandContext(ctx, "az", "keyvault", "secret", "set",
+			"--vault-name", vault,
+			"--name", name,
+			"--file", tmp.Name(),
+			"--encoding", "utf-8",
+			"-o", "none")
+	} else {
+		cmd = exec.CommandContext(ctx, "az", "rest",
+			"--method", "put",
+			"--url", "https://"+vault+"."+p.dnsSuffix+"/secrets/"+name+"?api-version="+setSecretAPIVersion,
+			"--resource", "https://"+p.dnsSuffix,
+			"--headers", "Content-Type=application/json",
+			"--body", "@"+tmp.Name(),
+			"-o", "none")
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if typed := classifyError(stderr.String(), vault, name); typed != nil {
+			return typed
+		}
+		return fmt.Errorf("failed to set secret %s: %w (stderr: %s)", name, err, stderr.String())
+	}
+	return nil
+}
+
+// DeleteVaultSecret deletes a secret; vaults with soft delete keep it recoverable
+func (p *Provider) DeleteVaultSecret(ctx context.Context, vault, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	args := append([]string{"keyvault", "secret", "delete"}, p.secretArgs(vault, name)...)
+	cmd := exec.CommandContext(ctx, "az", append(args, "-o", "none")...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if typed := classifyError(stderr.String(), vault, name); typed != nil {
+			return typed
+		}
+		return fmt.Errorf("failed to delete secret %s: %w (stderr: %s)", name, err, stderr.String())
+	}
+	return nil
+}
+
+// ListSecrets returns the names of all enabled secrets in the vault
+func (p *Provider) ListSecrets(ctx context.Context, vault string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	args := append([]string{"keyvault", "secret", "list"}, p.vaultArgs(vault)...)
+	cmd := exec.CommandContext(ctx, "az", append(args, "--query", "[?attributes.enabled].name", "-o", "json")...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if typed := classifyError(stderr.String(), vault, ""); typed != nil {
+			return nil, typed
+		}
+		return nil, fmt.Errorf("failed to list secrets: %w (stderr: %s)", err, stderr.String())
+	}
+
+	var names []string
+	if err := json.Unmarshal(stdout.Bytes(), &names); err != nil {
+		return nil, fmt.Errorf("failed to parse secret list: %w", err)
+	}
+	return names, nil
+}
+
+// CheckVaultAccess lists at most one secret, which is enough to tell a missing
+// vault or a missing role assignment apart from a missing login
+func (p *Provider) CheckVaultAccess(ctx context.Context, vault string) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	args := append([]string{"keyvault", "secret", "list"}, p.vaultArgs(vault)...)
+	cmd := exec.CommandContext(ctx, "az", append(args, "--maxresults", "1", "-o", "none")...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if typed := classifyError(stderr.String(), vault, ""); typed != nil {
+			return typed
+		}
+		return fmt.Errorf("failed to access vault %s: %w (stderr: %s)", vault, err, stderr.String())
+	}
+	return nil
+}
+
 // WarmToken attempts to refresh the access token
 func (p *Provider) WarmToken(ctx context.Context) error {
+	resource := "https://vault.azure.net"
+	if p.dnsSuffix != "" {
+		resource = "https://" + p.dnsSuffix
+	}
 	cmd := exec.CommandContext(ctx, "az", "account", "get-access-token",
-		"--resource", "https://vault.azure.net",
+		"--resource", resource,
 		"-o", "none")
 	return cmd.Run()
 }
@@ -125,8 +383,13 @@ func (e *NotFoundError) Error() string {
 	return fmt.Sprintf("secret %s not found in vault %s", e.Secret, e.Vault)
 }
 
+// Is lets errors.Is match provider.ErrNotFound
+func (e *NotFoundError) Is(target error) bool {
+	return target == provider.ErrNotFound
+}
+
 // IsNotFound checks if the error is a not found error
 func IsNotFound(err error) bool {
-	_, ok := err.(*NotFoundError)
-	return ok
+	var target *NotFoundError
+	return errors.As(err, &target)
 }
@@ -0,0 +1,165 @@
+// Package execprov implements provider.Provider by delegating to an external
+// command, so any secret store can be plugged in with a small script.
+//
+// The configured command is run through the shell with an operation and its
+// arguments appended. The vault name is passed in the YEET_VAULT environment
+// variable. Operations:
+//
+//	check        exit 0 if the backend is reachable and authenticated
+//	get NAME     print the secret value on stdout; exit 3 if it does not exist
+//	list         print one secret name per line
+//	set NAME     read the new value from stdin
+//	delete NAME  delete the secret; exit 3 if it does not exist
+//
+// A single trailing newline is stripped from get output. Any other non-zero
+// exit status is an error and the command's stderr is included in the message.
+package execprov
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/JayDubyaEey/yeet/internal/provider"
+)
+
+// ExitNotFound is the exit status a command uses to report a missing secret
+const ExitNotFound = 3
+
+// VaultEnv is the environment variable holding the vault name for the command
+const VaultEnv = "YEET_VAULT"
+
+// Provider runs an external command for each secret operation
+type Provider struct {
+	command string
+	timeout time.Duration
+}
+
+var (
+	_ provider.Provider = (*Provider)(nil)
+	_ provider.Lister   = (*Provider)(nil)
+	_ provider.Setter   = (*Provider)(nil)
+	_ provider.Deleter  = (*Provider)(nil)
+)
+
+// New creates a provider that runs command for each operation
+func New(command string) *Provider {
+	return &Provider{
+		command: command,
+		timeout: 30 * time.Second,
+	}
+}
+
+// EnsureLoggedIn runs the check operation
+func (p *Provider) EnsureLoggedIn(ctx context.Context) error {
+	if _, err := p.run(ctx, "", nil, "check"); err != nil {
+		return fmt.Errorf("provider command check failed: %w", err)
+	}
+	return nil
+}
+
+// GetSecret runs the get operation
+func (p *Provider) GetSecret(ctx context.Context, vault, name string) (string, error) {
+	out, err := p.run(ctx, vault, nil, "get", name)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == ExitNotFound {
+			return "", fmt.Errorf("secret %s not found in %s: %w", name, vault, provider.ErrNotFound)
+		}
+		return "", fmt.Errorf("failed to get secret %s: %w", name, err)
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(out, "\n"), "\r"), nil
+}
+
+// SecretExists reports whether get succeeds for name
+func (p *Provider) SecretExists(ctx context.Context, vault, name string) (bool, error) {
+	_, err := p.GetSecret(ctx, vault, name)
+	if err != nil {
+		if provider.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ListSecrets runs the list operation
+func (p *Provider) ListSecrets(ctx context.Context, vault string) ([]string, error) {
+	out, err := p.run(ctx, vault, nil, "list")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	var names []string
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		if name := strings.TrimSpace(scanner.Text()); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, scanner.Err()
+}
+
+// SetVaultSecret runs the set operation, passing value on stdin
+func (p *Provider) SetVaultSecret(ctx context.Context, vault, name, value string) error {
+	if _, err := p.run(ctx, vault, strings.NewReader(value), "set", name); err != nil {
+		return fmt.Errorf("failed to set secret %s: %w", name, err)
+	}
+	return nil
+}
+
+// DeleteVaultSecret runs the delete operation
+func (p *Provider) DeleteVaultSecret(ctx context.Context, vault, name string) error {
+	if _, err := p.run(ctx, vault, nil, "delete", name); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == ExitNotFound {
+			return fmt.Errorf("secret %s not found in %s: %w", name, vault, provider.ErrNotFound)
+		}
+		return fmt.Errorf("failed to delete secret %s: %w", name, err)
+	}
+	return nil
+}
+
+// run executes the command with args appended and returns its stdout
+func (p *Provider) run(ctx context.Context, vault string, stdin *strings.Reader, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", p.command+" "+strings.Join(args, " "))
+	} else {
+		// "$@" keeps each argument intact however the command is quoted
+		cmd = exec.CommandContext(ctx, "sh", "-c", p.command+` "$@"`, "yeet")
+		cmd.Args = append(cmd.Args, args...)
+	}
+	cmd.Env = append(os.Environ(), VaultEnv+"="+vault)
+	// Children of the shell can hold the pipes open after it is killed
+	cmd.WaitDelay = time.Second
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			// Either our own timeout or the caller's deadline or cancellation
+			return "", fmt.Errorf("%s did not finish: %w", args[0], ctx.Err())
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w (stderr: %s)", err, msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
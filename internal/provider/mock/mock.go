@@ -0,0 +1,178 @@
+// Package mock provides an in-memory provider.Provider for tests
+package mock
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/JayDubyaEey/yeet/internal/provider"
+)
+
+// Call records a single method invocation on the mock
+type Call struct {
+	Method string
+	Vault  string
+	Name   string
+}
+
+// Provider is a programmable provider.Provider. Secrets are keyed by name and
+// shared across vaults; unknown names return an error matching provider.ErrNotFound.
+type Provider struct {
+	mu       sync.Mutex
+	secrets  map[string]string
+	errors   map[string]error
+	loginErr error
+	calls    []Call
+}
+
+var (
+	_ provider.Provider       = (*Provider)(nil)
+	_ provider.MetadataGetter = (*Provider)(nil)
+	_ provider.Lister         = (*Provider)(nil)
+	_ provider.Setter         = (*Provider)(nil)
+	_ provider.Deleter        = (*Provider)(nil)
+)
+
+// New creates a mock provider with the given secrets
+func New(secrets map[string]string) *Provider {
+	p := &Provider{
+		secrets: make(map[string]string),
+		errors:  make(map[string]error),
+	}
+	for name, value := range secrets {
+		p.secrets[name] = value
+	}
+	return p
+}
+
+// SetSecret sets or replaces a secret value
+func (p *Provider) SetSecret(name, value string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.secrets[name] = value
+}
+
+// DeleteSecret removes a secret so lookups report not found
+func (p *Provider) DeleteSecret(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.secrets, name)
+}
+
+// SetError makes lookups of name fail with err; pass nil to clear it
+func (p *Provider) SetError(name string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err == nil {
+		delete(p.errors, name)
+		return
+	}
+	p.errors[name] = err
+}
+
+// SetLoginError makes EnsureLoggedIn fail with err; pass nil to clear it
+func (p *Provider) SetLoginError(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.loginErr = err
+}
+
+// Calls returns a copy of the recorded calls in order
+func (p *Provider) Calls() []Call {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Call(nil), p.calls...)
+}
+
+// EnsureLoggedIn implements provider.Provider
+func (p *Provider) EnsureLoggedIn(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, Call{Method: "EnsureLoggedIn"})
+	return p.loginErr
+}
+
+// GetSecret implements provider.Provider
+func (p *Provider) GetSecret(ctx context.Context, vault, name string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, Call{Method: "GetSecret", Vault: vault, Name: name})
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if err, ok := p.errors[name]; ok {
+		return "", err
+	}
+	if value, ok := p.secrets[name]; ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("%w: %s in vault %s", provider.ErrNotFound, name, vault)
+}
+
+// GetSecretWithMetadata implements provider.MetadataGetter; secrets are
+// always enabled and carry no other attributes
+func (p *Provider) GetSecretWithMetadata(ctx context.Context, vault, name string) (provider.Secret, error) {
+	value, err := p.GetSecret(ctx, vault, name)
+	if err != nil {
+		return provider.Secret{}, err
+	}
+	return provider.Secret{Value: value, Enabled: true}, nil
+}
+
+// SecretExists implements provider.Provider
+func (p *Provider) SecretExists(ctx context.Context, vault, name string) (bool, error) {
+	_, err := p.GetSecret(ctx, vault, name)
+	if err != nil {
+		if provider.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ListSecrets implements provider.Lister, returning the names of all secrets in sorted order
+func (p *Provider) ListSecrets(ctx context.Context, vault string) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, Call{Method: "ListSecrets", Vault: vault})
+
+	names := make([]string, 0, len(p.secrets))
+	for name := range p.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SetVaultSecret implements provider.Setter; a programmed error for name is returned instead of storing
+func (p *Provider) SetVaultSecret(ctx context.Context, vault, name, value string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, Call{Method: "SetVaultSecret", Vault: vault, Name: name})
+
+	if err, ok := p.errors[name]; ok {
+		return err
+	}
+	p.secrets[name] = value
+	return nil
+}
+
+// DeleteVaultSecret implements provider.Deleter; deleting an unknown name reports not found
+func (p *Provider) DeleteVaultSecret(ctx context.Context, vault, name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, Call{Method: "DeleteVaultSecret", Vault: vault, Name: name})
+
+	if err, ok := p.errors[name]; ok {
+		return err
+	}
+	if _, ok := p.secrets[name]; !ok {
+		return fmt.Errorf("secret %s not found in %s: %w", name, vault, provider.ErrNotFound)
+	}
+	delete(p.secrets, name)
+	return nil
+}
@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Provider is the set of secret operations the CLI needs from a backend
+type Provider interface {
+	// EnsureLoggedIn returns an error if the backend is not authenticated
+	EnsureLoggedIn(ctx context.Context) error
+	// GetSecret returns the value of a secret, or an error matching ErrNotFound
+	GetSecret(ctx context.Context, vault, name string) (string, error)
+	// SecretExists reports whether a secret exists
+	SecretExists(ctx context.Context, vault, name string) (bool, error)
+}
+
+// Secret is a secret value together with its attributes
+type Secret struct {
+	Value       string
+	Version     string
+	ContentType string
+	Tags        map[string]string
+	Enabled     bool
+	Updated     time.Time
+}
+
+// MetadataGetter is implemented by providers that can return a secret's
+// attributes along with its value in a single call
+type MetadataGetter interface {
+	GetSecretWithMetadata(ctx context.Context, vault, name string) (Secret, error)
+}
+
+// GetWithMetadata returns the secret with its attributes when p supports
+// them, and otherwise just the value, marked enabled
+func GetWithMetadata(ctx context.Context, p Provider, vault, name string) (Secret, error) {
+	if m, ok := p.(MetadataGetter); ok {
+		return m.GetSecretWithMetadata(ctx, vault, name)
+	}
+	value, err := p.GetSecret(ctx, vault, name)
+	if err != nil {
+		return Secret{}, err
+	}
+	return Secret{Value: value, Enabled: true}, nil
+}
+
+// Lister is implemented by providers that can enumerate secret names
+type Lister interface {
+	ListSecrets(ctx context.Context, vault string) ([]string, error)
+}
+
+// Setter is implemented by providers that can create or update secrets
+type Setter interface {
+	SetVaultSecret(ctx context.Context, vault, name, value string) error
+}
+
+// Deleter is implemented by providers that can delete secrets
+type Deleter interface {
+	DeleteVaultSecret(ctx context.Context, vault, name string) error
+}
+
+// TokenWarmer is implemented by providers that can refresh credentials ahead of use
+type TokenWarmer interface {
+	WarmToken(ctx context.Context) error
+}
+
+// LoginHinter is implemented by providers that can name the command that
+// fixes a failed EnsureLoggedIn
+type LoginHinter interface {
+	LoginHint() string
+}
+
+// VaultAccessChecker is implemented by providers that can cheaply confirm a
+// vault exists and the current identity may read it
+type VaultAccessChecker interface {
+	CheckVaultAccess(ctx context.Context, vault string) error
+}
+
+// ErrUnsupported is returned when a provider cannot perform an operation
+var ErrUnsupported = errors.New("not supported by this provider")
+
+// CheckVaultAccess confirms p can reach vault, falling back to listing its
+// secrets when p has no cheaper check, and returns ErrUnsupported if it can do
+// neither
+func CheckVaultAccess(ctx context.Context, p Provider, vault string) error {
+	if c, ok := p.(VaultAccessChecker); ok {
+		return c.CheckVaultAccess(ctx, vault)
+	}
+	if l, ok := p.(Lister); ok {
+		_, err := l.ListSecrets(ctx, vault)
+		return err
+	}
+	return ErrUnsupported
+}
+
+// ErrNotFound is matched (via errors.Is) by every provider's not found error
+var ErrNotFound = errors.New("secret not found")
+
+// IsNotFound checks if the error is a not found error from any provider
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// ErrDisabled is matched by errors for secrets that exist but are disabled,
+// so their value cannot be read
+var ErrDisabled = errors.New("secret is disabled")
+
+// IsDisabled checks if the error is a disabled secret error from any provider
+func IsDisabled(err error) bool {
+	return errors.Is(err, ErrDisabled)
+}
+
+// ErrVaultNotFound is matched by errors for a vault that does not exist, so
+// every secret lookup in it would fail the same way
+var ErrVaultNotFound = errors.New("vault not found")
+
+// IsVaultNotFound checks if the error is a vault not found error from any provider
+func IsVaultNotFound(err error) bool {
+	return errors.Is(err, ErrVaultNotFound)
+}
+
+// ErrAuth is matched by errors for a missing or expired login, or a missing
+// permission on the vault
+var ErrAuth = errors.New("not authorized")
+
+// IsAuth checks if the error is an authentication or authorization error from any provider
+func IsAuth(err error) bool {
+	return errors.Is(err, ErrAuth)
+}
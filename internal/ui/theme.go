@@ -0,0 +1,109 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Symbol identifies a decorative glyph whose rendering depends on the theme
+type Symbol int
+
+const (
+	SymbolSearch Symbol = iota
+	SymbolSummary
+	SymbolMatch
+	SymbolWarning
+	SymbolCelebrate
+	SymbolHint
+	SymbolCheck
+	SymbolCross
+	SymbolBullet
+)
+
+// Theme controls message prefixes and symbols
+type Theme struct {
+	name string
+
+	infoPrefix    string
+	warnPrefix    string
+	successPrefix string
+	errorPrefix   string
+	tracePrefix   string
+
+	symbols map[Symbol]string
+}
+
+var defaultTheme = Theme{
+	name:          "default",
+	infoPrefix:    "ℹ ",
+	warnPrefix:    "⚠ ",
+	successPrefix: "✔ ",
+	errorPrefix:   "✖ ",
+	tracePrefix:   "⏱ ",
+	symbols: map[Symbol]string{
+		SymbolSearch:    "🔍",
+		SymbolSummary:   "📊",
+		SymbolMatch:     "✅",
+		SymbolWarning:   "⚠️ ",
+		SymbolCelebrate: "🎉",
+		SymbolHint:      "💡",
+		SymbolCheck:     "✓",
+		SymbolCross:     "⚠",
+		SymbolBullet:    "•",
+	},
+}
+
+// asciiTheme avoids non-ASCII characters entirely
+var asciiTheme = Theme{
+	name:          "ascii",
+	infoPrefix:    "[INFO] ",
+	warnPrefix:    "[WARN] ",
+	successPrefix: "[OK] ",
+	errorPrefix:   "[ERROR] ",
+	tracePrefix:   "[TRACE] ",
+	symbols: map[Symbol]string{
+		SymbolCheck:  "+",
+		SymbolCross:  "!",
+		SymbolBullet: "-",
+	},
+}
+
+// minimalTheme drops decoration except where it carries meaning
+var minimalTheme = Theme{
+	name:        "minimal",
+	warnPrefix:  "warning: ",
+	errorPrefix: "error: ",
+	symbols: map[Symbol]string{
+		SymbolBullet: "-",
+	},
+}
+
+var themes = map[string]Theme{
+	defaultTheme.name: defaultTheme,
+	asciiTheme.name:   asciiTheme,
+	minimalTheme.name: minimalTheme,
+}
+
+// ThemeNames lists the available theme names
+func ThemeNames() []string {
+	return []string{defaultTheme.name, asciiTheme.name, minimalTheme.name}
+}
+
+// SetTheme selects the named theme; call before Setup
+func SetTheme(name string) error {
+	t, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %q: must be one of %s", name, strings.Join(ThemeNames(), ", "))
+	}
+	theme = t
+	return nil
+}
+
+// Sym returns the current theme's rendering of s followed by a space,
+// or an empty string if the theme has no glyph for it
+func Sym(s Symbol) string {
+	if g := theme.symbols[s]; g != "" {
+		return g + " "
+	}
+	return ""
+}
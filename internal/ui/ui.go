@@ -4,21 +4,22 @@ import (
 	"fmt"
 	"github.com/fatih/color"
 	"os"
+	"sync/atomic"
 )
 
 var (
 	noColor bool
 	verbose bool
+	// muted is atomic since run --on-secret-change polls in the background
+	muted atomic.Bool
 
-	infoPrefix    = "ℹ "
-	warnPrefix    = "⚠ "
-	successPrefix = "✔ "
-	errorPrefix   = "✖ "
+	theme = defaultTheme
 
 	infoColor    = color.New(color.FgCyan)
 	warnColor    = color.New(color.FgYellow)
 	successColor = color.New(color.FgGreen)
 	errorColor   = color.New(color.FgRed)
+	traceColor   = color.New(color.FgMagenta)
 )
 
 // Setup configures the UI package
@@ -28,37 +29,80 @@ func Setup(disableColor, verboseMode bool) {
 
 	if noColor {
 		color.NoColor = true
-		// Use ASCII fallbacks
-		infoPrefix = "[INFO] "
-		warnPrefix = "[WARN] "
-		successPrefix = "[OK] "
-		errorPrefix = "[ERROR] "
+		// Use ASCII fallbacks unless another theme was chosen explicitly
+		if theme.name == defaultTheme.name {
+			theme = asciiTheme
+		}
 	}
 }
 
+// SetMuted suppresses Info, Warn and Success output while on; errors and
+// traces are always printed
+func SetMuted(on bool) {
+	muted.Store(on)
+}
+
 // Info prints an info message
 func Info(format string, args ...interface{}) {
-	if !verbose {
+	if !verbose || muted.Load() {
 		return
 	}
 	msg := fmt.Sprintf(format, args...)
-	infoColor.Printf("%s%s\n", infoPrefix, msg)
+	infoColor.Printf("%s%s\n", theme.infoPrefix, msg)
 }
 
 // Warn prints a warning message
 func Warn(format string, args ...interface{}) {
+	if muted.Load() {
+		return
+	}
 	msg := fmt.Sprintf(format, args...)
-	warnColor.Printf("%s%s\n", warnPrefix, msg)
+	warnColor.Printf("%s%s\n", theme.warnPrefix, msg)
+}
+
+// WarnStderr prints a warning message to stderr, for warnings any command can
+// raise, including ones whose stdout is read by scripts
+func WarnStderr(format string, args ...interface{}) {
+	if muted.Load() {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	warnColor.Fprintf(os.Stderr, "%s%s\n", theme.warnPrefix, msg)
 }
 
 // Success prints a success message
 func Success(format string, args ...interface{}) {
+	if muted.Load() {
+		return
+	}
 	msg := fmt.Sprintf(format, args...)
-	successColor.Printf("%s%s\n", successPrefix, msg)
+	successColor.Printf("%s%s\n", theme.successPrefix, msg)
 }
 
 // Error prints an error message
 func Error(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
-	errorColor.Fprintf(os.Stderr, "%s%s\n", errorPrefix, msg)
+	errorColor.Fprintf(os.Stderr, "%s%s\n", theme.errorPrefix, msg)
+}
+
+// Trace prints a timing/diagnostic message to stderr
+func Trace(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	traceColor.Fprintf(os.Stderr, "%s%s\n", theme.tracePrefix, msg)
+}
+
+// Print prints an undecorated line
+func Print(format string, args ...interface{}) {
+	fmt.Printf(format+"\n", args...)
+}
+
+// Item prints an indented list entry prefixed with the theme's rendering of sym
+func Item(sym Symbol, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	fmt.Printf("  %s%s\n", Sym(sym), msg)
+}
+
+// Blank prints an empty separator line
+func Blank() {
+	fmt.Println()
 }